@@ -27,7 +27,10 @@ var deleteCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		service, err := loam.New(cmd.Context(), root, loam.WithAdapter(adapter), loam.WithVersioning(!nover), loam.WithMustExist(true), loam.WithStrict(strict))
+		opts := []loam.Option{loam.WithAdapter(adapter), loam.WithVersioning(!nover), loam.WithMustExist(true), loam.WithStrict(strict), loam.WithScriptHooks(!noHooks)}
+		opts = append(opts, workspaceOptions(root)...)
+
+		service, err := loam.New(cmd.Context(), root, opts...)
 		if err != nil {
 			fmt.Printf("Error initializing loam: %v\n", err)
 			os.Exit(1)
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aretw0/loam"
+	"github.com/aretw0/loam/pkg/webhooks"
+	"github.com/spf13/cobra"
+)
+
+var hooksJSON bool
+
+// hooksCmd groups the webhook subcommands.
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Inspect and run the vault webhooks",
+	Long: `Webhooks are configured in .loam/webhooks.json. Each webhook receives a signed
+JSON POST for every change matching its ID pattern and event types. Deliveries
+that fail are retried with backoff and kept in .loam/webhooks until delivered.`,
+}
+
+var hooksStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the delivery status of each webhook",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		statuses, err := webhooks.Status(vaultSystemPath())
+		if err != nil {
+			fatal("Failed to read webhooks status", err)
+		}
+
+		if hooksJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(statuses); err != nil {
+				fatal("Failed to encode JSON", err)
+			}
+			return
+		}
+
+		if len(statuses) == 0 {
+			fmt.Println("No webhooks configured.")
+			return
+		}
+		for _, st := range statuses {
+			fmt.Printf("%s (%s)\n", st.Name, st.URL)
+			fmt.Printf("  delivered: %d, pending: %d, undelivered: %d\n", st.Delivered, st.Pending, st.Undelivered)
+			if !st.LastAttempt.IsZero() {
+				fmt.Printf("  last attempt: %s (status %d)\n", st.LastAttempt.Format(time.RFC3339), st.LastStatus)
+			}
+			if st.LastError != "" {
+				fmt.Printf("  last error: %s\n", st.LastError)
+			}
+		}
+	},
+}
+
+var hooksRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Watch the vault and deliver changes to the webhooks until interrupted",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		systemPath := vaultSystemPath()
+		cfg, err := webhooks.LoadConfig(systemPath)
+		if err != nil {
+			fatal("Failed to load webhooks", err)
+		}
+		if len(cfg.Webhooks) == 0 {
+			fmt.Println("No webhooks configured.")
+			return
+		}
+
+		root := filepath.Dir(systemPath)
+		opts := []loam.Option{
+			loam.WithAdapter(adapter),
+			loam.WithVersioning(!nover),
+			loam.WithMustExist(true),
+			loam.WithLogger(slog.Default()),
+		}
+		opts = append(opts, workspaceOptions(root)...)
+		service, err := loam.New(cmd.Context(), root, opts...)
+		if err != nil {
+			fatal("Failed to initialize loam", err)
+		}
+
+		fmt.Printf("Delivering changes to %d webhook(s)...\n", len(cfg.Webhooks))
+		dispatcher := webhooks.NewDispatcher(service, systemPath, cfg, webhooks.WithLogger(slog.Default()))
+		if err := dispatcher.Run(cmd.Context()); err != nil {
+			fatal("Webhooks stopped", err)
+		}
+	},
+}
+
+// vaultSystemPath returns the system directory of the vault at the current directory.
+func vaultSystemPath() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		fatal("Failed to get CWD", err)
+	}
+	root, err := loam.FindVaultRoot(wd)
+	if err != nil {
+		fatal("Not a Loam vault (no .loam, .git, or loam.json found). Run 'loam init' first.", nil)
+	}
+	return filepath.Join(root, ".loam")
+}
+
+func init() {
+	rootCmd.AddCommand(hooksCmd)
+	hooksCmd.AddCommand(hooksStatusCmd, hooksRunCmd)
+	hooksStatusCmd.Flags().BoolVar(&hooksJSON, "json", false, "Output in JSON format")
+}
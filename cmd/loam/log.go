@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/aretw0/loam"
+	"github.com/aretw0/loam/pkg/core"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logJSON           bool
+	logAllowedSigners string
+	logRequireSigned  bool
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log [id]",
+	Short: "Show the revision history of a document",
+	Long: `Show the revisions of a document, newest first, with the verification status of
+each commit signature. Unsigned or untrusted revisions are flagged.
+Use --allowed-signers to verify SSH signatures.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := args[0]
+		wd, err := os.Getwd()
+		if err != nil {
+			fatal("Failed to get CWD", err)
+		}
+
+		root, err := loam.FindVaultRoot(wd)
+		if err != nil {
+			fatal("Not a Loam vault (no .loam, .git, or loam.json found). Run 'loam init' first.", nil)
+		}
+
+		opts := []loam.Option{
+			loam.WithAdapter(adapter),
+			loam.WithMustExist(true),
+			loam.WithLogger(slog.Default()),
+		}
+		if logAllowedSigners != "" {
+			opts = append(opts, loam.WithAllowedSigners(logAllowedSigners))
+		}
+		opts = append(opts, workspaceOptions(root)...)
+
+		service, err := loam.New(cmd.Context(), root, opts...)
+		if err != nil {
+			fatal("Failed to initialize loam", err)
+		}
+
+		revisions, err := service.History(cmd.Context(), id)
+		if err != nil {
+			fatal("Failed to read history", err)
+		}
+
+		if logJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(revisions); err != nil {
+				fatal("Failed to encode JSON", err)
+			}
+		} else {
+			for _, rev := range revisions {
+				flag := ""
+				if rev.Signature != core.SignatureGood {
+					flag = fmt.Sprintf(" [%s]", rev.Signature)
+				}
+				fmt.Printf("%s %s %s <%s> %s%s\n",
+					rev.Hash[:7],
+					time.Unix(rev.Timestamp, 0).Format(time.RFC3339),
+					rev.Author, rev.AuthorEmail, rev.Message, flag)
+			}
+		}
+
+		if logRequireSigned {
+			for _, rev := range revisions {
+				if rev.Signature != core.SignatureGood {
+					fmt.Fprintf(os.Stderr, "Error: revision %s is %s\n", rev.Hash[:7], rev.Signature)
+					os.Exit(1)
+				}
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logCmd)
+	logCmd.Flags().BoolVar(&logJSON, "json", false, "Output in JSON format")
+	logCmd.Flags().StringVar(&logAllowedSigners, "allowed-signers", "", "SSH allowed signers file used to verify signatures")
+	logCmd.Flags().BoolVar(&logRequireSigned, "require-signed", false, "Exit with an error if any revision is not signed by a trusted key")
+}
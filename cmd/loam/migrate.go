@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aretw0/loam"
+	"github.com/aretw0/loam/pkg/core"
+	"github.com/aretw0/loam/pkg/scripthooks"
+	"github.com/aretw0/loam/pkg/typed"
+)
+
+// migrationsConfigName is the file, in the vault system directory, declaring the
+// migration steps of each document type.
+const migrationsConfigName = "migrations.json"
+
+// migrationsConfig maps type names to their documents and migration steps.
+type migrationsConfig struct {
+	Types map[string]struct {
+		Pattern string   `json:"pattern"`
+		Steps   []string `json:"steps"`
+	} `json:"types"`
+}
+
+var (
+	migrateType   string
+	migrateDryRun bool
+	migrateMsg    string
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate --type <name>",
+	Short: "Upgrade the documents of a type to its current schema version",
+	Long: `Upgrade the documents of a type declared in .loam/migrations.json, e.g.:
+
+  {"types": {"ticket": {"pattern": "tickets/**", "steps": ["migrations/ticket/1", "migrations/ticket/2"]}}}
+
+Documents carry their version in the schema_version metadata field (missing means 0).
+The Nth step upgrades documents from version N-1 to N: it is an executable (relative to
+.loam) that reads the metadata as JSON on stdin and writes the upgraded metadata on stdout.
+Every outdated document is rewritten in a single transaction (one commit); if a step
+fails, nothing is written.
+
+Go programs register the same steps with typed.NewMigrations and typed.WithMigrations.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		systemPath := vaultSystemPath()
+		root := filepath.Dir(systemPath)
+		migrations, err := loadMigrations(cmd.Context(), root, systemPath, migrateType)
+		if err != nil {
+			fatal("Failed to load migrations", err)
+		}
+
+		opts := []loam.Option{
+			loam.WithAdapter(adapter),
+			loam.WithMustExist(true),
+			loam.WithStrict(strict),
+			loam.WithLogger(slog.Default()),
+			loam.WithScriptHooks(!noHooks),
+		}
+		if cmd.Flags().Lookup("nover").Changed {
+			opts = append(opts, loam.WithVersioning(!nover))
+		}
+		opts = append(opts, workspaceOptions(root)...)
+		service, err := loam.New(cmd.Context(), root, opts...)
+		if err != nil {
+			fatal("Failed to initialize loam", err)
+		}
+
+		if migrateDryRun {
+			docs, err := service.ListDocuments(cmd.Context())
+			if err != nil {
+				fatal("Failed to list documents", err)
+			}
+			count := 0
+			for _, doc := range docs {
+				_, changed, err := migrations.Upgrade(doc.ID, doc.Metadata)
+				if err != nil {
+					fatal("Migration failed", err)
+				}
+				if changed {
+					fmt.Println(doc.ID)
+					count++
+				}
+			}
+			fmt.Printf("%d document(s) would be migrated to version %d.\n", count, migrations.Version())
+			return
+		}
+
+		msg := migrateMsg
+		if msg == "" {
+			msg = fmt.Sprintf("upgrade %s documents to schema version %d", migrateType, migrations.Version())
+		}
+		ctx := context.WithValue(cmd.Context(), core.ChangeReasonKey, loam.FormatChangeReason(loam.CommitTypeChore, "migrate", msg, ""))
+		ids, err := typed.Migrate(ctx, service, migrations)
+		if err != nil {
+			fatal("Migration failed", err)
+		}
+		for _, id := range ids {
+			fmt.Println(id)
+		}
+		fmt.Printf("%d document(s) migrated to version %d.\n", len(ids), migrations.Version())
+	},
+}
+
+// loadMigrations builds the migrations of a type from the vault configuration.
+func loadMigrations(ctx context.Context, vaultPath, systemPath, name string) (*typed.Migrations, error) {
+	var cfg migrationsConfig
+	data, err := os.ReadFile(filepath.Join(systemPath, migrationsConfigName))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", migrationsConfigName, err)
+	}
+	t, ok := cfg.Types[name]
+	if !ok {
+		return nil, fmt.Errorf("type %q is not declared in %s", name, migrationsConfigName)
+	}
+
+	migrations := typed.NewMigrations(t.Pattern)
+	for i, step := range t.Steps {
+		path := filepath.Join(systemPath, filepath.FromSlash(step))
+		migrations.Register(scriptStep(ctx, vaultPath, path, i+1))
+	}
+	return migrations, nil
+}
+
+// scriptStep runs an executable upgrading metadata to version, as a JSON filter.
+func scriptStep(ctx context.Context, vaultPath, path string, version int) typed.MigrationStep {
+	return func(metadata map[string]any) (map[string]any, error) {
+		stdin, err := json.Marshal(metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, scripthooks.DefaultTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, path)
+		cmd.Dir = vaultPath
+		cmd.WaitDelay = time.Second
+		cmd.Env = append(os.Environ(), "LOAM_VAULT="+vaultPath, "LOAM_VERSION="+strconv.Itoa(version))
+		cmd.Stdin = bytes.NewReader(stdin)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && stderr.Len() > 0 {
+				return nil, fmt.Errorf("%s: %s", filepath.Base(path), strings.TrimSpace(stderr.String()))
+			}
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+
+		var upgraded map[string]any
+		decoder := json.NewDecoder(&stdout)
+		decoder.UseNumber()
+		if err := decoder.Decode(&upgraded); err != nil {
+			return nil, fmt.Errorf("%s: invalid output: %w", filepath.Base(path), err)
+		}
+		return upgraded, nil
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+	migrateCmd.Flags().StringVar(&migrateType, "type", "", "Document type declared in .loam/migrations.json")
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "List the documents that would be migrated without writing them")
+	migrateCmd.Flags().StringVarP(&migrateMsg, "message", "m", "", "Change reason (audit note)")
+	migrateCmd.MarkFlagRequired("type")
+}
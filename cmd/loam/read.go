@@ -44,13 +44,16 @@ var readCmd = &cobra.Command{
 			}
 		}
 
-		service, err := loam.New(cmd.Context(), root,
+		opts := []loam.Option{
 			loam.WithAdapter(adapter),
 			loam.WithVersioning(useVersioning),
 			loam.WithMustExist(true),
 			loam.WithStrict(strict),
 			loam.WithLogger(slog.Default()),
-		)
+		}
+		opts = append(opts, workspaceOptions(root)...)
+
+		service, err := loam.New(cmd.Context(), root, opts...)
 		if err != nil {
 			fmt.Printf("Error initializing loam: %v\n", err)
 			os.Exit(1)
@@ -9,10 +9,12 @@ import (
 )
 
 var (
-	verbose bool
-	nover   bool
-	adapter string
-	strict  bool
+	verbose   bool
+	nover     bool
+	adapter   string
+	strict    bool
+	workspace string
+	noHooks   bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -46,4 +48,6 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&nover, "nover", false, "Run in no-versioning mode (no git operations)")
 	rootCmd.PersistentFlags().StringVar(&adapter, "adapter", "fs", "Storage adapter to use (fs)")
 	rootCmd.PersistentFlags().BoolVar(&strict, "strict", false, "Enable strict type checking (preserves numeric fidelity)")
+	rootCmd.PersistentFlags().StringVar(&workspace, "workspace", "", "Operate on a workspace instead of the main tree (defaults to the active workspace)")
+	rootCmd.PersistentFlags().BoolVar(&noHooks, "no-hooks", false, "Skip the vault's script hooks (.loam/hooks)")
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/spf13/cobra"
+
+	"github.com/aretw0/loam/pkg/schema"
+)
+
+var schemaVSCode bool
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema [id]",
+	Short: "Show the vault schemas",
+	Long: `Without arguments, list the ID patterns mapped to schemas in .loam/schemas.json.
+With a document ID, print the schema files that apply to it.
+With --vscode, print the json.schemas and yaml.schemas settings that make VS Code
+(and its YAML extension) validate the vault files against the same schemas.
+
+Go programs can export schemas generated from their types with schema.Export.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		systemPath := vaultSystemPath()
+		cfg, err := schema.LoadConfig(systemPath)
+		if err != nil {
+			fatal("Failed to load schemas", err)
+		}
+
+		if schemaVSCode {
+			printVSCodeSettings(filepath.Base(systemPath), cfg)
+			return
+		}
+
+		if len(args) == 0 {
+			if len(cfg.Schemas) == 0 {
+				fmt.Println("No schemas configured.")
+				return
+			}
+			for _, rule := range cfg.Schemas {
+				fmt.Printf("%s -> %s\n", rule.Pattern, rule.Schema)
+			}
+			return
+		}
+
+		matched := false
+		for _, rule := range cfg.Schemas {
+			if ok, _ := doublestar.Match(rule.Pattern, args[0]); !ok {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(systemPath, filepath.FromSlash(rule.Schema)))
+			if err != nil {
+				fatal("Failed to read schema", err)
+			}
+			if matched {
+				fmt.Println()
+			}
+			matched = true
+			os.Stdout.Write(data)
+		}
+		if !matched {
+			fmt.Fprintf(os.Stderr, "No schema applies to %s.\n", args[0])
+			os.Exit(1)
+		}
+	},
+}
+
+// printVSCodeSettings prints the editor settings mapping vault files to the schemas.
+func printVSCodeSettings(systemDir string, cfg schema.Config) {
+	type jsonSchema struct {
+		FileMatch []string `json:"fileMatch"`
+		URL       string   `json:"url"`
+	}
+	settings := struct {
+		JSON []jsonSchema        `json:"json.schemas"`
+		YAML map[string][]string `json:"yaml.schemas"`
+	}{JSON: []jsonSchema{}, YAML: map[string][]string{}}
+
+	for _, rule := range cfg.Schemas {
+		url := "./" + systemDir + "/" + rule.Schema
+		// Patterns match IDs; IDs may leave out the file extension.
+		files := []string{"/" + rule.Pattern}
+		if !strings.HasSuffix(rule.Pattern, "*") {
+			files = append(files, "/"+rule.Pattern+".*")
+		}
+		settings.JSON = append(settings.JSON, jsonSchema{FileMatch: files, URL: url})
+		settings.YAML[url] = append(settings.YAML[url], files...)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(settings); err != nil {
+		fatal("Failed to encode JSON", err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+	schemaCmd.Flags().BoolVar(&schemaVSCode, "vscode", false, "Print VS Code settings mapping the vault files to the schemas")
+}
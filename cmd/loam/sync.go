@@ -1,11 +1,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 
 	"github.com/aretw0/loam"
+	"github.com/aretw0/loam/pkg/scripthooks"
 	"github.com/spf13/cobra"
 )
 
@@ -26,7 +28,11 @@ It integrates remote changes and pushes local changes.`,
 			loam.WithAdapter(adapter),
 			loam.WithVersioning(!nover),
 			loam.WithLogger(slog.Default()),
+			loam.WithScriptHooks(!noHooks),
 		); err != nil {
+			if errors.Is(err, scripthooks.ErrRejected) {
+				fatal("Sync aborted", err)
+			}
 			// User friendly error handling
 			fmt.Fprintf(os.Stderr, "Error: Sync failed: %v\n", err)
 			fmt.Println("Tip: Ensure you have a remote configured ('git remote add origin <url>') and you are online.")
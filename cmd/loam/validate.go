@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aretw0/loam"
+	"github.com/aretw0/loam/pkg/core"
+	"github.com/aretw0/loam/pkg/schema"
+)
+
+var (
+	validateAll  bool
+	validateJSON bool
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [id...]",
+	Short: "Validate documents against the vault schemas",
+	Long: `Validate documents against the JSON Schemas mapped to their IDs in .loam/schemas.json.
+Pass document IDs, or --all to scan the whole vault. Exits with status 1 if any document is invalid.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if validateAll == (len(args) > 0) {
+			fmt.Println("Error: pass document IDs or --all")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		systemPath := vaultSystemPath()
+		validator, err := schema.Load(systemPath)
+		if err != nil {
+			fatal("Failed to load schemas", err)
+		}
+
+		opts := []loam.Option{
+			loam.WithAdapter(adapter),
+			loam.WithMustExist(true),
+			loam.WithStrict(strict),
+			loam.WithLogger(slog.Default()),
+		}
+		if cmd.Flags().Lookup("nover").Changed {
+			opts = append(opts, loam.WithVersioning(!nover))
+		}
+		root := filepath.Dir(systemPath)
+		opts = append(opts, workspaceOptions(root)...)
+		service, err := loam.New(cmd.Context(), root, opts...)
+		if err != nil {
+			fatal("Failed to initialize loam", err)
+		}
+
+		var docs []core.Document
+		if validateAll {
+			if docs, err = service.ListDocuments(cmd.Context()); err != nil {
+				fatal("Failed to list documents", err)
+			}
+		} else {
+			for _, id := range args {
+				doc, err := service.GetDocument(cmd.Context(), id)
+				if err != nil {
+					fatal(fmt.Sprintf("Failed to read %s", id), err)
+				}
+				docs = append(docs, doc)
+			}
+		}
+
+		invalid := []*core.ValidationError{}
+		checked := 0
+		for _, doc := range docs {
+			if !validator.Matches(doc.ID) {
+				continue
+			}
+			checked++
+			var verr *core.ValidationError
+			if err := validator.Validate(doc); errors.As(err, &verr) {
+				invalid = append(invalid, verr)
+			} else if err != nil {
+				fatal(fmt.Sprintf("Failed to validate %s", doc.ID), err)
+			}
+		}
+
+		if validateJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(invalid); err != nil {
+				fatal("Failed to encode JSON", err)
+			}
+		} else {
+			for _, verr := range invalid {
+				fmt.Printf("%s\n", verr.ID)
+				for _, fe := range verr.Errors {
+					fmt.Printf("  %s\n", fe)
+				}
+			}
+			fmt.Printf("%d document(s) checked, %d invalid.\n", checked, len(invalid))
+		}
+		if len(invalid) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().BoolVar(&validateAll, "all", false, "Validate every document in the vault")
+	validateCmd.Flags().BoolVar(&validateJSON, "json", false, "Output the invalid documents in JSON format")
+}
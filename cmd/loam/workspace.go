@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/aretw0/loam"
+	"github.com/aretw0/loam/pkg/adapters/fs"
+	"github.com/spf13/cobra"
+)
+
+// workspaceCmd groups the workspace subcommands.
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Manage branch-based workspaces (drafts, staging)",
+	Long: `Workspaces stage content changes on a separate git branch, checked out inside the
+system directory so the main tree is untouched. Use 'switch' to make read/write/list/delete
+operate on a workspace, and 'merge' to publish it atomically.`,
+}
+
+var workspaceCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a new workspace from the current HEAD",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ws := openWorkspaces(cmd)
+		if err := ws.Create(cmd.Context(), args[0]); err != nil {
+			fatal("Failed to create workspace", err)
+		}
+		fmt.Printf("Workspace '%s' created.\n", args[0])
+	},
+}
+
+var workspaceSwitchCmd = &cobra.Command{
+	Use:   "switch [name]",
+	Short: "Make a workspace active (omit name to return to the main tree)",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		ws := openWorkspaces(cmd)
+		if err := ws.Switch(cmd.Context(), name); err != nil {
+			fatal("Failed to switch workspace", err)
+		}
+		if name == "" {
+			fmt.Println("Switched to main tree.")
+			return
+		}
+		fmt.Printf("Switched to workspace '%s'.\n", name)
+	},
+}
+
+var workspaceMergeCmd = &cobra.Command{
+	Use:   "merge [name]",
+	Short: "Merge a workspace into the main tree",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ws := openWorkspaces(cmd)
+		evts, err := ws.Merge(cmd.Context(), args[0])
+		if err != nil {
+			fatal("Failed to merge workspace", err)
+		}
+		for _, e := range evts {
+			fmt.Println(e)
+		}
+		fmt.Printf("Workspace '%s' merged (%d documents changed).\n", args[0], len(evts))
+	},
+}
+
+var workspaceDiscardCmd = &cobra.Command{
+	Use:   "discard [name]",
+	Short: "Delete a workspace and drop its unmerged changes",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ws := openWorkspaces(cmd)
+		if err := ws.Discard(cmd.Context(), args[0]); err != nil {
+			fatal("Failed to discard workspace", err)
+		}
+		fmt.Printf("Workspace '%s' discarded.\n", args[0])
+	},
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List workspaces",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ws := openWorkspaces(cmd)
+		names, err := ws.List(cmd.Context())
+		if err != nil {
+			fatal("Failed to list workspaces", err)
+		}
+		active := ws.Active()
+		for _, name := range names {
+			marker := " "
+			if name == active {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, name)
+		}
+	},
+}
+
+// openWorkspaces opens the vault at the current directory and returns its workspace manager.
+func openWorkspaces(cmd *cobra.Command) *fs.Workspaces {
+	wd, err := os.Getwd()
+	if err != nil {
+		fatal("Failed to get CWD", err)
+	}
+	root, err := loam.FindVaultRoot(wd)
+	if err != nil {
+		fatal("Not a Loam vault (no .loam, .git, or loam.json found). Run 'loam init' first.", nil)
+	}
+
+	repo, err := loam.Init(cmd.Context(), root,
+		loam.WithAdapter(adapter),
+		loam.WithMustExist(true),
+		loam.WithLogger(slog.Default()),
+	)
+	if err != nil {
+		fatal("Failed to initialize loam", err)
+	}
+	fsRepo, ok := repo.(*fs.Repository)
+	if !ok {
+		fatal("Workspaces are only supported by the fs adapter", nil)
+	}
+	return fsRepo.Workspaces()
+}
+
+// workspaceOptions returns the option binding commands to the selected workspace:
+// the --workspace flag if set, otherwise the active workspace of the vault.
+func workspaceOptions(root string) []loam.Option {
+	name := workspace
+	if name == "" {
+		name = fs.ActiveWorkspace(root, ".loam")
+	}
+	if name == "" {
+		return nil
+	}
+	return []loam.Option{loam.WithWorkspace(name)}
+}
+
+func init() {
+	rootCmd.AddCommand(workspaceCmd)
+	workspaceCmd.AddCommand(workspaceCreateCmd, workspaceSwitchCmd, workspaceMergeCmd, workspaceDiscardCmd, workspaceListCmd)
+}
@@ -23,6 +23,9 @@ var (
 	writeScope   string
 	writeSet     map[string]string
 	writeRaw     bool
+	writeAuthor  string
+	writeCoAuth  []string
+	writeTrailer map[string]string
 )
 
 // writeCmd represents the write command
@@ -75,6 +78,7 @@ var writeCmd = &cobra.Command{
 			loam.WithAdapter(adapter),
 			loam.WithLogger(slog.Default()),
 			loam.WithStrict(strict),
+			loam.WithScriptHooks(!noHooks),
 		}
 
 		// Only force versioning config if the flag was explicitly set.
@@ -82,6 +86,7 @@ var writeCmd = &cobra.Command{
 		if cmd.Flags().Lookup("nover").Changed {
 			opts = append(opts, loam.WithVersioning(!nover))
 		}
+		opts = append(opts, workspaceOptions(root)...)
 
 		service, err := loam.New(cmd.Context(), root, opts...)
 		if err != nil {
@@ -145,6 +150,19 @@ var writeCmd = &cobra.Command{
 		// Pass commit message via context (Adapter specific requirement)
 		ctx := context.WithValue(context.Background(), core.ChangeReasonKey, finalMsg)
 
+		// Attribution (author, co-authors, trailers)
+		if writeAuthor != "" || len(writeCoAuth) > 0 || len(writeTrailer) > 0 {
+			info := core.ChangeInfo{CoAuthors: writeCoAuth, Trailers: writeTrailer}
+			if writeAuthor != "" {
+				name, email, ok := parseAuthor(writeAuthor)
+				if !ok {
+					fatal("Invalid --author (expected \"Name <email>\")", nil)
+				}
+				info.AuthorName, info.AuthorEmail = name, email
+			}
+			ctx = context.WithValue(ctx, core.ChangeInfoKey, info)
+		}
+
 		if err := service.SaveDocument(ctx, writeID, writeContent, meta); err != nil {
 			fatal("Failed to save document", err)
 		}
@@ -162,5 +180,18 @@ func init() {
 	writeCmd.Flags().StringVarP(&writeScope, "scope", "s", "", "Change scope")
 	writeCmd.Flags().StringToStringVar(&writeSet, "set", nil, "Set metadata fields (key=value)")
 	writeCmd.Flags().BoolVar(&writeRaw, "raw", false, "Treat input as raw document (parse metadata from content)")
+	writeCmd.Flags().StringVar(&writeAuthor, "author", "", "Commit author (\"Name <email>\")")
+	writeCmd.Flags().StringArrayVar(&writeCoAuth, "co-author", nil, "Co-author (\"Name <email>\"), repeatable")
+	writeCmd.Flags().StringToStringVar(&writeTrailer, "trailer", nil, "Commit trailers (key=value, e.g. Ticket=OPS-42)")
 	writeCmd.MarkFlagRequired("id")
 }
+
+// parseAuthor splits "Name <email>" into its parts.
+func parseAuthor(s string) (name, email string, ok bool) {
+	open := strings.LastIndex(s, "<")
+	end := strings.LastIndex(s, ">")
+	if open == -1 || end < open {
+		return "", "", false
+	}
+	return strings.TrimSpace(s[:open]), strings.TrimSpace(s[open+1 : end]), true
+}
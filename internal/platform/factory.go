@@ -2,8 +2,13 @@ package platform
 
 import (
 	"context"
+	"path/filepath"
+	"time"
 
+	"github.com/aretw0/loam/pkg/adapters/fs"
 	"github.com/aretw0/loam/pkg/core"
+	"github.com/aretw0/loam/pkg/schema"
+	"github.com/aretw0/loam/pkg/scripthooks"
 )
 
 // svc, err := loam.New(context.Background(), "./path/to/vault", loam.WithVersioning(false))
@@ -27,8 +32,85 @@ func New(ctx context.Context, uri string, opts ...Option) (*core.Service, error)
 	if val, ok := o.config["event_buffer"].(int); ok {
 		coreOpts = append(coreOpts, core.WithEventBuffer(val))
 	}
+	if val, ok := o.config["overflow_policy"].(core.OverflowPolicy); ok {
+		coreOpts = append(coreOpts, core.WithOverflowPolicy(val))
+	}
+	if hooks, ok := o.config["hooks"].([]core.Hook); ok {
+		coreOpts = append(coreOpts, core.WithHook(hooks...))
+	}
+
+	if runner := scriptHooks(repo, o); runner != nil {
+		coreOpts = append(coreOpts, core.WithHook(runner.Hook()))
+	}
+	// Innermost, so schemas see the document as rewritten by the other hooks.
+	validator, err := schemaValidator(repo, o)
+	if err != nil {
+		return nil, err
+	}
+	if validator != nil {
+		coreOpts = append(coreOpts, core.WithHook(validator.Hook()))
+	}
 
 	service := core.NewService(repo, coreOpts...)
 
+	if name, _ := o.config["workspace"].(string); name != "" {
+		return service.Workspace(ctx, name)
+	}
+
 	return service, nil
 }
+
+// vaultSystemPath returns the vault root and system directory of a repository on disk.
+func vaultSystemPath(repo core.Repository, o *options) (root, systemPath string, ok bool) {
+	fsRepo, ok := repo.(*fs.Repository)
+	if !ok {
+		return "", "", false
+	}
+	systemDir, _ := o.config["system_dir"].(string)
+	if systemDir == "" {
+		systemDir = ".loam"
+	}
+	return fsRepo.Path, filepath.Join(fsRepo.Path, systemDir), true
+}
+
+// scriptHooks returns the runner of the vault's script hooks, or nil if they are
+// disabled or the repository is not a vault on disk.
+func scriptHooks(repo core.Repository, o *options) *scripthooks.Runner {
+	if enabled, ok := o.config["script_hooks"].(bool); ok && !enabled {
+		return nil
+	}
+	root, systemPath, ok := vaultSystemPath(repo, o)
+	if !ok {
+		return nil
+	}
+	timeout, _ := o.config["script_hook_timeout"].(time.Duration)
+	return scripthooks.New(root, systemPath,
+		scripthooks.WithTimeout(timeout),
+		scripthooks.WithLogger(o.logger),
+	)
+}
+
+// schemaValidator returns the validator of the vault's schemas and those added with
+// WithSchema, or nil if validation is disabled or there are no schemas.
+func schemaValidator(repo core.Repository, o *options) (*schema.Validator, error) {
+	if enabled, ok := o.config["schema_validation"].(bool); ok && !enabled {
+		return nil, nil
+	}
+	validator := schema.New()
+	if _, systemPath, ok := vaultSystemPath(repo, o); ok {
+		var err error
+		if validator, err = schema.Load(systemPath); err != nil {
+			return nil, err
+		}
+	}
+	registered, _ := o.config["schemas"].([]registeredSchema)
+	for _, r := range registered {
+		if err := validator.Register(r.pattern, r.schema); err != nil {
+			return nil, err
+		}
+	}
+	if validator.Empty() {
+		return nil, nil
+	}
+	return validator, nil
+}
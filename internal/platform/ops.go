@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/aretw0/loam/pkg/adapters/fs"
 	"github.com/aretw0/loam/pkg/core"
+	"github.com/aretw0/loam/pkg/git"
 )
 
 // Init initializes a new Loam vault based on the provided configuration.
@@ -64,6 +66,21 @@ func initFS(path string, o *options) (core.Repository, error) {
 	systemDir, _ := o.config["system_dir"].(string)
 	errorHandler, _ := o.config["watcher_error_handler"].(func(error))
 
+	signingFormat, _ := o.config["signing_format"].(string)
+	signingKey, _ := o.config["signing_key"].(string)
+	allowedSigners, _ := o.config["allowed_signers"].(string)
+	var signing *git.Signing
+	if signingKey != "" || allowedSigners != "" {
+		signing = &git.Signing{Format: signingFormat, Key: signingKey, AllowedSigners: allowedSigners}
+	}
+
+	groupCommitWindow, _ := o.config["group_commit_window"].(time.Duration)
+	groupCommitMaxBatch, _ := o.config["group_commit_max_batch"].(int)
+	richEvents, _ := o.config["rich_events"].(bool)
+	changeLog, _ := o.config["change_log"].(bool)
+	polling, _ := o.config["polling"].(bool)
+	pollInterval, _ := o.config["poll_interval"].(time.Duration)
+
 	isReadOnly, _ := o.config["read_only"].(bool)
 	// Check if dev_safety is explicitly set. Use boolean assertion AND check existence.
 	// Default to true (safe) if not present.
@@ -151,6 +168,16 @@ func initFS(path string, o *options) (core.Repository, error) {
 		MarkdownBodyKey:   markdownBodyKey,
 		ErrorHandler:      errorHandler,
 		ReadOnly:          isReadOnly,
+		Signing:           signing,
+
+		GroupCommitWindow:   groupCommitWindow,
+		GroupCommitMaxBatch: groupCommitMaxBatch,
+
+		RichEvents: richEvents,
+		ChangeLog:  changeLog,
+
+		Polling:      polling,
+		PollInterval: pollInterval,
 	}
 
 	repo := fs.NewRepository(repoConfig)
@@ -207,5 +234,8 @@ func Sync(ctx context.Context, uri string, opts ...Option) error {
 		return fmt.Errorf("repository does not support synchronization")
 	}
 
+	if runner := scriptHooks(repo, o); runner != nil {
+		return runner.Sync(ctx, syncable.Sync)
+	}
 	return syncable.Sync(ctx)
 }
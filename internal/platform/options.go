@@ -2,6 +2,7 @@ package platform
 
 import (
 	"log/slog"
+	"time"
 
 	"github.com/aretw0/loam/pkg/core"
 )
@@ -108,6 +109,61 @@ func WithEventBuffer(size int) Option {
 	}
 }
 
+// WithOverflowPolicy sets what the event broker does when a subscriber's buffer is full.
+// Defaults to core.OverflowBlock.
+func WithOverflowPolicy(policy core.OverflowPolicy) Option {
+	return func(o *options) {
+		o.config["overflow_policy"] = policy
+	}
+}
+
+// WithHook adds middleware hooks around the service's saves, deletes and commits.
+func WithHook(hooks ...core.Hook) Option {
+	return func(o *options) {
+		existing, _ := o.config["hooks"].([]core.Hook)
+		o.config["hooks"] = append(existing, hooks...)
+	}
+}
+
+// WithScriptHooks enables or disables the executable hooks in <SystemDir>/hooks
+// (pre-save, post-save, pre-sync, post-sync). They are enabled by default.
+func WithScriptHooks(enabled bool) Option {
+	return func(o *options) {
+		o.config["script_hooks"] = enabled
+	}
+}
+
+// WithScriptHookTimeout sets how long a script hook may run before it is killed.
+// Zero means default (10s).
+func WithScriptHookTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.config["script_hook_timeout"] = d
+	}
+}
+
+// WithSchemaValidation enables or disables validation against the JSON Schemas
+// configured in <SystemDir>/schemas.json. It is enabled by default.
+func WithSchemaValidation(enabled bool) Option {
+	return func(o *options) {
+		o.config["schema_validation"] = enabled
+	}
+}
+
+// registeredSchema is a schema added with WithSchema.
+type registeredSchema struct {
+	pattern string
+	schema  []byte
+}
+
+// WithSchema validates the documents whose ID matches pattern against a JSON Schema
+// (e.g. schema.For[T]()), in addition to those configured in the vault.
+func WithSchema(pattern string, schema []byte) Option {
+	return func(o *options) {
+		existing, _ := o.config["schemas"].([]registeredSchema)
+		o.config["schemas"] = append(existing, registeredSchema{pattern: pattern, schema: schema})
+	}
+}
+
 // WithStrict enables strict mode for all default serializers.
 // When enabled, numbers in JSON/YAML/Markdown will be parsed as json.Number (string based)
 // to preserve precision of large integers.
@@ -164,3 +220,66 @@ func WithDevSafety(enabled bool) Option {
 		o.config["dev_safety"] = enabled
 	}
 }
+
+// WithWorkspace binds the service to an existing workspace (e.g. a draft branch).
+// Only applies to loam.New; an empty name means the main tree.
+func WithWorkspace(name string) Option {
+	return func(o *options) {
+		o.config["workspace"] = name
+	}
+}
+
+// WithSigning signs every commit produced by the vault.
+// format is "ssh", "openpgp" (GPG) or "x509"; key is the SSH key path or GPG key ID.
+func WithSigning(format, key string) Option {
+	return func(o *options) {
+		o.config["signing_format"] = format
+		o.config["signing_key"] = key
+	}
+}
+
+// WithAllowedSigners sets the SSH allowed signers file used to verify commit signatures.
+// GPG signatures are verified against the user's keyring instead.
+func WithAllowedSigners(path string) Option {
+	return func(o *options) {
+		o.config["allowed_signers"] = path
+	}
+}
+
+// WithGroupCommit coalesces concurrent saves into fewer commits.
+// Saves arriving within window are committed together; maxBatch (if > 0) flushes early
+// once that many saves are pending. Each caller still receives the outcome of its commit.
+func WithGroupCommit(window time.Duration, maxBatch int) Option {
+	return func(o *options) {
+		o.config["group_commit_window"] = window
+		o.config["group_commit_max_batch"] = maxBatch
+	}
+}
+
+// WithRichEvents attaches the document, its previous version, a metadata diff, the
+// originating commit and the origin (local or external) to watch events.
+// Changes made by this process are then delivered as local events.
+func WithRichEvents(enabled bool) Option {
+	return func(o *options) {
+		o.config["rich_events"] = enabled
+	}
+}
+
+// WithChangeLog keeps a durable, append-only log of applied changes in the system
+// directory. Every change gets a sequence number (core.Cursor), so consumers can
+// resume with Service.Changes or Service.WatchFrom after a restart.
+func WithChangeLog(enabled bool) Option {
+	return func(o *options) {
+		o.config["change_log"] = enabled
+	}
+}
+
+// WithPolling makes Watch detect changes by scanning the vault every interval instead of
+// using fsnotify, for network (NFS, SMB) and container filesystems that do not deliver
+// change notifications. A zero interval uses the default (2s).
+func WithPolling(interval time.Duration) Option {
+	return func(o *options) {
+		o.config["polling"] = true
+		o.config["poll_interval"] = interval
+	}
+}
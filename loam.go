@@ -3,6 +3,7 @@ package loam
 import (
 	"context"
 	"log/slog"
+	"time"
 
 	"github.com/aretw0/loam/internal/platform"
 	"github.com/aretw0/loam/pkg/core"
@@ -72,6 +73,41 @@ func WithEventBuffer(size int) Option {
 	return platform.WithEventBuffer(size)
 }
 
+// WithOverflowPolicy sets what the event broker does when a subscriber's buffer is full
+// (block, drop-oldest, drop-newest or disconnect).
+func WithOverflowPolicy(policy core.OverflowPolicy) Option {
+	return platform.WithOverflowPolicy(policy)
+}
+
+// WithHook adds middleware hooks around the service's saves, deletes and transaction
+// commits (see core.Hook). Hooks can validate, modify or reject changes.
+func WithHook(hooks ...core.Hook) Option {
+	return platform.WithHook(hooks...)
+}
+
+// WithScriptHooks enables or disables the executable hooks in <SystemDir>/hooks
+// (pre-save, post-save, pre-sync, post-sync; see package scripthooks). Enabled by default.
+func WithScriptHooks(enabled bool) Option {
+	return platform.WithScriptHooks(enabled)
+}
+
+// WithScriptHookTimeout sets how long a script hook may run before it is killed (10s by default).
+func WithScriptHookTimeout(d time.Duration) Option {
+	return platform.WithScriptHookTimeout(d)
+}
+
+// WithSchemaValidation enables or disables validation of saves, commits and Reconcile
+// against the JSON Schemas in <SystemDir>/schemas.json (see package schema). Enabled by default.
+func WithSchemaValidation(enabled bool) Option {
+	return platform.WithSchemaValidation(enabled)
+}
+
+// WithSchema validates the documents whose ID matches pattern against a JSON Schema,
+// such as one generated from a typed model with schema.For[T]().
+func WithSchema(pattern string, schema []byte) Option {
+	return platform.WithSchema(pattern, schema)
+}
+
 // WithStrict enables strict mode for all default serializers.
 // When enabled, numbers in JSON/YAML/Markdown will be parsed as json.Number (string based)
 // to preserve precision of large integers.
@@ -112,6 +148,41 @@ func WithDevSafety(enabled bool) Option {
 	return platform.WithDevSafety(enabled)
 }
 
+// WithWorkspace binds the service created by New to an existing workspace.
+func WithWorkspace(name string) Option {
+	return platform.WithWorkspace(name)
+}
+
+// WithSigning signs every commit with an SSH key ("ssh") or GPG key ("openpgp").
+func WithSigning(format, key string) Option {
+	return platform.WithSigning(format, key)
+}
+
+// WithAllowedSigners sets the SSH allowed signers file used to verify commit signatures.
+func WithAllowedSigners(path string) Option {
+	return platform.WithAllowedSigners(path)
+}
+
+// WithGroupCommit coalesces concurrent saves arriving within window (or up to maxBatch) into one commit.
+func WithGroupCommit(window time.Duration, maxBatch int) Option {
+	return platform.WithGroupCommit(window, maxBatch)
+}
+
+// WithRichEvents attaches the document, previous version, metadata diff, commit and origin to watch events.
+func WithRichEvents(enabled bool) Option {
+	return platform.WithRichEvents(enabled)
+}
+
+// WithChangeLog keeps a durable log of applied changes, for Service.Changes and Service.WatchFrom.
+func WithChangeLog(enabled bool) Option {
+	return platform.WithChangeLog(enabled)
+}
+
+// WithPolling makes Watch scan the vault every interval instead of using fsnotify (e.g. on NFS/SMB).
+func WithPolling(interval time.Duration) Option {
+	return platform.WithPolling(interval)
+}
+
 // --- Factory ---
 
 // New creates a new Loam Service.
@@ -124,16 +195,27 @@ func Init(ctx context.Context, path string, opts ...Option) (core.Repository, er
 	return platform.Init(ctx, path, opts...)
 }
 
+// --- Transactions ---
+
+// WithMultiTransaction runs fn with one transaction per service (each on its own vault)
+// and commits them atomically with two-phase commit. In-doubt transactions left by a
+// crash are resolved when the vaults are next opened.
+func WithMultiTransaction(ctx context.Context, services []*core.Service, fn func(txs []core.Transaction) error) error {
+	return core.WithMultiTransaction(ctx, services, fn)
+}
+
 // --- Typed Factories ---
 
 // NewTypedRepository creates a type-safe wrapper around an existing repository.
-func NewTypedRepository[T any](repo core.Repository) *typed.Repository[T] {
-	return typed.NewRepository[T](repo)
+// Options such as typed.WithValidation validate T on save and load.
+func NewTypedRepository[T any](repo core.Repository, opts ...typed.Option) *typed.Repository[T] {
+	return typed.NewRepository[T](repo, opts...)
 }
 
 // NewTypedService creates a type-safe wrapper around an existing service.
-func NewTypedService[T any](svc *core.Service) *typed.Service[T] {
-	return typed.NewService[T](svc)
+// Options such as typed.WithValidation validate T on save and load.
+func NewTypedService[T any](svc *core.Service, opts ...typed.Option) *typed.Service[T] {
+	return typed.NewService[T](svc, opts...)
 }
 
 // OpenTypedRepository simplifies creating a TypedRepository from a path.
@@ -127,6 +127,19 @@ func (c *cache) Get(relPath string, currentMtime time.Time) (*indexEntry, bool)
 	return entry, true
 }
 
+// Peek returns a copy of the entry for relPath regardless of freshness, or nil if absent.
+func (c *cache) Peek(relPath string) *indexEntry {
+	c.index.mu.RLock()
+	defer c.index.mu.RUnlock()
+
+	entry, ok := c.index.Entries[relPath]
+	if !ok {
+		return nil
+	}
+	cp := *entry
+	return &cp
+}
+
 // Set updates an entry in the cache.
 func (c *cache) Set(relPath string, entry *indexEntry) {
 	c.index.mu.Lock()
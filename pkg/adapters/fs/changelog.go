@@ -0,0 +1,270 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// changeLogName is the file, inside SystemDir, holding the change log.
+const changeLogName = "changes.log"
+
+var errChangeLogDisabled = errors.New("change log is not enabled")
+
+// changeRecord is a line of the change log.
+type changeRecord struct {
+	Seq       core.Cursor      `json:"seq"`
+	Type      core.EventType   `json:"type"`
+	ID        string           `json:"id"`
+	Path      string           `json:"path"` // Relative to the vault, slash-separated.
+	Timestamp int64            `json:"ts"`
+	Commit    string           `json:"commit,omitempty"`
+	Origin    core.EventOrigin `json:"origin,omitempty"`
+}
+
+func (rec changeRecord) event() core.Event {
+	return core.Event{
+		Type:      rec.Type,
+		ID:        rec.ID,
+		Timestamp: rec.Timestamp,
+		Path:      rec.Path,
+		Commit:    rec.Commit,
+		Origin:    rec.Origin,
+		Cursor:    rec.Seq,
+	}
+}
+
+// changeLog is the append-only log of applied changes (see Config.ChangeLog).
+// Every record gets the next sequence number; records are JSON lines. Appends
+// are serialized across processes by a lock on the file.
+type changeLog struct {
+	path string
+
+	mu     sync.Mutex
+	offset int64         // End of the last complete record scanned.
+	seq    core.Cursor   // Last sequence number scanned or assigned.
+	notify chan struct{} // Closed and replaced on every append.
+}
+
+func newChangeLog(vaultPath, systemDir string) *changeLog {
+	return &changeLog{
+		path:   filepath.Join(vaultPath, systemDir, changeLogName),
+		notify: make(chan struct{}),
+	}
+}
+
+// append logs the events, setting their Cursor. paths holds the file of each event.
+func (l *changeLog) append(evts []core.Event, paths []string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create change log directory: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open change log: %w", err)
+	}
+	defer f.Close()
+
+	// Other processes (or Repository instances) on the vault append to the same
+	// log: hold the file lock from the scan to the write so that sequence numbers
+	// are never assigned twice.
+	unlock, err := lockFile(f)
+	if err != nil {
+		return fmt.Errorf("failed to lock change log: %w", err)
+	}
+	defer unlock()
+
+	// Pick up records appended by other processes since our last write.
+	recs, end, partial, err := scanChanges(f, l.offset, 0)
+	if err != nil {
+		return err
+	}
+	if len(recs) > 0 {
+		l.seq = max(l.seq, recs[len(recs)-1].Seq)
+	}
+
+	var buf bytes.Buffer
+	if partial > 0 {
+		// Terminate a record left incomplete by a crash; it is skipped when read.
+		buf.WriteByte('\n')
+	}
+	for i := range evts {
+		l.seq++
+		evts[i].Cursor = l.seq
+		data, err := json.Marshal(changeRecord{
+			Seq:       l.seq,
+			Type:      evts[i].Type,
+			ID:        evts[i].ID,
+			Path:      paths[i],
+			Timestamp: evts[i].Timestamp,
+			Commit:    evts[i].Commit,
+			Origin:    evts[i].Origin,
+		})
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to append to change log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync change log: %w", err)
+	}
+	l.offset = end + partial + int64(buf.Len())
+
+	close(l.notify)
+	l.notify = make(chan struct{})
+	return nil
+}
+
+// changed returns a channel closed on the next append.
+func (l *changeLog) changed() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.notify
+}
+
+// read returns the records after since, starting at byte offset, and the offset
+// to continue from.
+func (l *changeLog) read(offset int64, since core.Cursor) ([]changeRecord, int64, error) {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, offset, nil
+	}
+	if err != nil {
+		return nil, offset, fmt.Errorf("failed to open change log: %w", err)
+	}
+	defer f.Close()
+
+	recs, end, _, err := scanChanges(f, offset, since)
+	return recs, end, err
+}
+
+// scanChanges parses the complete records from offset on, keeping those after since.
+// It returns the end of the last complete record and the length of a trailing partial one.
+func scanChanges(f *os.File, offset int64, since core.Cursor) ([]changeRecord, int64, int64, error) {
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, 0, fmt.Errorf("failed to read change log: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, offset, 0, fmt.Errorf("failed to read change log: %w", err)
+	}
+
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	var recs []changeRecord
+	for _, line := range bytes.Split(data[:complete], []byte("\n")) {
+		var rec changeRecord
+		if len(line) == 0 || json.Unmarshal(line, &rec) != nil {
+			continue
+		}
+		if rec.Seq > since {
+			recs = append(recs, rec)
+		}
+	}
+	return recs, offset + int64(complete), int64(len(data) - complete), nil
+}
+
+// logChanges appends changes made to the vault to the change log, setting their Cursor.
+func (r *Repository) logChanges(evts []core.Event, paths []string) {
+	if !r.config.ChangeLog || r.config.ReadOnly || len(evts) == 0 {
+		return
+	}
+	if err := r.changes.append(evts, paths); err != nil && r.config.Logger != nil {
+		r.config.Logger.Error("failed to append to change log", "err", err)
+	}
+}
+
+// Changes implements core.ChangeLog.
+func (r *Repository) Changes(ctx context.Context, since core.Cursor) ([]core.Event, error) {
+	if !r.config.ChangeLog {
+		return nil, errChangeLogDisabled
+	}
+	recs, _, err := r.changes.read(0, since)
+	if err != nil {
+		return nil, err
+	}
+	evts := make([]core.Event, len(recs))
+	for i, rec := range recs {
+		evts[i] = rec.event()
+	}
+	return evts, nil
+}
+
+// WatchFrom implements core.ChangeLog.
+//
+// It watches the vault (so external changes get logged), reconciles changes made
+// while no process was running, replays the log after since and then delivers
+// every new record. Events are read from the log, so they carry a Cursor but
+// no rich payload.
+func (r *Repository) WatchFrom(ctx context.Context, since core.Cursor, pattern string) (<-chan core.Event, error) {
+	if !r.config.ChangeLog {
+		return nil, errChangeLogDisabled
+	}
+
+	live, err := r.Watch(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if !r.config.ReadOnly {
+		if _, err := r.Reconcile(ctx); err != nil {
+			return nil, fmt.Errorf("failed to reconcile: %w", err)
+		}
+	}
+
+	out := make(chan core.Event, 100)
+	go func() {
+		defer close(out)
+
+		var offset int64
+		cursor := since
+		for {
+			// Subscribe before reading, so an append in between is not missed.
+			changed := r.changes.changed()
+
+			recs, next, err := r.changes.read(offset, cursor)
+			if err != nil {
+				if r.config.ErrorHandler != nil {
+					r.config.ErrorHandler(err)
+				}
+			}
+			offset = next
+			for _, rec := range recs {
+				cursor = rec.Seq
+				if !r.matchPattern(pattern, rec.Path) {
+					continue
+				}
+				select {
+				case out <- rec.event():
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+			case _, ok := <-live:
+				// Live events only drive the watcher, which logs them.
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,173 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+func TestChangeLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	open := func() *Repository {
+		repo := NewRepository(Config{
+			Path:      tmpDir,
+			Gitless:   true,
+			SystemDir: ".loam",
+			Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+			ChangeLog: true,
+		})
+		if err := repo.Initialize(context.Background()); err != nil {
+			t.Fatalf("failed to init repo: %v", err)
+		}
+		return repo
+	}
+	ctx := context.Background()
+	repo := open()
+
+	t.Run("Local Changes", func(t *testing.T) {
+		for _, id := range []string{"a", "b"} {
+			if err := repo.Save(ctx, core.Document{ID: id, Content: id}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := repo.Delete(ctx, "a"); err != nil {
+			t.Fatal(err)
+		}
+
+		changes, err := repo.Changes(ctx, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []struct {
+			id  string
+			typ core.EventType
+		}{{"a", core.EventCreate}, {"b", core.EventCreate}, {"a", core.EventDelete}}
+		if len(changes) != len(want) {
+			t.Fatalf("expected %d changes, got %+v", len(want), changes)
+		}
+		for i, w := range want {
+			c := changes[i]
+			if c.ID != w.id || c.Type != w.typ || c.Cursor != core.Cursor(i+1) || c.Origin != core.OriginLocal {
+				t.Errorf("change %d: unexpected %+v", i, c)
+			}
+		}
+
+		since, err := repo.Changes(ctx, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(since) != 1 || since[0].Cursor != 3 {
+			t.Errorf("expected only the change after cursor 2, got %+v", since)
+		}
+	})
+
+	t.Run("Resume After Restart", func(t *testing.T) {
+		// Offline edit, made while no process is running.
+		if err := os.WriteFile(filepath.Join(tmpDir, "c.md"), []byte("offline"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		repo = open()
+		watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		events, err := repo.WatchFrom(watchCtx, 3, "**/*")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		next := func() core.Event {
+			t.Helper()
+			select {
+			case e := <-events:
+				return e
+			case <-watchCtx.Done():
+				t.Fatal("timed out waiting for change")
+			}
+			return core.Event{}
+		}
+
+		// Replay: the offline edit is found by reconciliation and logged.
+		if e := next(); e.ID != "c" || e.Type != core.EventCreate || e.Cursor != 4 || e.Origin != core.OriginExternal {
+			t.Errorf("unexpected replayed change: %+v", e)
+		}
+
+		// Live
+		if err := repo.Save(ctx, core.Document{ID: "d", Content: "live"}); err != nil {
+			t.Fatal(err)
+		}
+		if e := next(); e.ID != "d" || e.Cursor != 5 || e.Origin != core.OriginLocal {
+			t.Errorf("unexpected live change: %+v", e)
+		}
+
+		// The watcher starts in the background; let it set up before the external write,
+		// which is written aside and renamed in so that it is a single event.
+		time.Sleep(100 * time.Millisecond)
+		staged := filepath.Join(t.TempDir(), "e.md")
+		if err := os.WriteFile(staged, []byte("external"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(staged, filepath.Join(tmpDir, "e.md")); err != nil {
+			t.Fatal(err)
+		}
+		if e := next(); e.ID != "e" || e.Cursor != 6 || e.Origin != core.OriginExternal {
+			t.Errorf("unexpected external change: %+v", e)
+		}
+
+		select {
+		case e := <-events:
+			t.Errorf("unexpected duplicate change: %+v", e)
+		case <-time.After(300 * time.Millisecond):
+		}
+
+		// Let the watcher stop before the vault is removed.
+		cancel()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	t.Run("Concurrent Repositories", func(t *testing.T) {
+		// Like the CLI and a server sharing a vault: each scans the log on its own.
+		a, b := open(), open()
+		before, err := a.Changes(ctx, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		const n = 100
+		var wg sync.WaitGroup
+		for name, r := range map[string]*Repository{"a": a, "b": b} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range n {
+					id := fmt.Sprintf("concurrent/%s%d", name, i)
+					evt := core.Event{Type: core.EventCreate, ID: id, Timestamp: time.Now().Unix(), Origin: core.OriginLocal}
+					if err := r.changes.append([]core.Event{evt}, []string{id + ".md"}); err != nil {
+						t.Error(err)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		changes, err := b.Changes(ctx, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != len(before)+2*n {
+			t.Fatalf("expected %d changes, got %d", len(before)+2*n, len(changes))
+		}
+		for i, c := range changes {
+			if c.Cursor != core.Cursor(i+1) {
+				t.Fatalf("change %d has cursor %d: sequence numbers must be unique and monotonic", i, c.Cursor)
+			}
+		}
+	})
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package fs
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile takes an exclusive lock on f, waiting for other holders (including
+// other processes). It is released by the returned function or by closing f.
+//
+// The locked byte is far past the end of the file, so readers are not blocked.
+func lockFile(f *os.File) (func(), error) {
+	h := windows.Handle(f.Fd())
+	ol := &windows.Overlapped{OffsetHigh: 0x7fffffff}
+	if err := windows.LockFileEx(h, windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, ol); err != nil {
+		return nil, err
+	}
+	return func() { windows.UnlockFileEx(h, 0, 1, 0, ol) }, nil
+}
@@ -0,0 +1,157 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aretw0/loam/pkg/git"
+)
+
+// groupCommitter coalesces concurrent saves into fewer git commits.
+//
+// Requests arriving within the window (or until maxBatch requests are pending) are
+// committed together. Each caller blocks until the commit containing its file is
+// made, and receives that commit's outcome.
+type groupCommitter struct {
+	repo     *Repository
+	window   time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	pending []*commitRequest
+	timer   *time.Timer
+}
+
+type commitRequest struct {
+	file   string
+	reason string
+	opts   git.CommitOptions
+	done   chan error
+}
+
+func newGroupCommitter(repo *Repository, window time.Duration, maxBatch int) *groupCommitter {
+	return &groupCommitter{
+		repo:     repo,
+		window:   window,
+		maxBatch: maxBatch,
+	}
+}
+
+// submit queues a file for the next group commit and waits for the result.
+// If ctx is cancelled while waiting, ctx.Err() is returned but the file stays queued.
+func (g *groupCommitter) submit(ctx context.Context, file, reason string, opts git.CommitOptions) error {
+	req := &commitRequest{file: file, reason: reason, opts: opts, done: make(chan error, 1)}
+
+	g.mu.Lock()
+	g.pending = append(g.pending, req)
+	switch {
+	case g.maxBatch > 0 && len(g.pending) >= g.maxBatch:
+		batch := g.takeLocked()
+		g.mu.Unlock()
+		go g.flush(batch)
+	case g.timer == nil:
+		g.timer = time.AfterFunc(g.window, func() {
+			g.mu.Lock()
+			batch := g.takeLocked()
+			g.mu.Unlock()
+			g.flush(batch)
+		})
+		g.mu.Unlock()
+	default:
+		g.mu.Unlock()
+	}
+
+	select {
+	case err := <-req.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// takeLocked detaches the pending batch. Caller must hold g.mu.
+func (g *groupCommitter) takeLocked() []*commitRequest {
+	batch := g.pending
+	g.pending = nil
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	return batch
+}
+
+// flush commits a batch. Requests with different attribution (author, trailers)
+// cannot share a commit, so the batch is split into one commit per attribution.
+func (g *groupCommitter) flush(batch []*commitRequest) {
+	if len(batch) == 0 {
+		return
+	}
+
+	var order []string
+	groups := make(map[string][]*commitRequest)
+	for _, req := range batch {
+		key := req.opts.Author + "\x00" + strings.Join(req.opts.Trailers, "\x00")
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], req)
+	}
+
+	unlock, err := g.repo.git.Lock()
+	if err != nil {
+		finish(batch, fmt.Errorf("failed to acquire git lock: %w", err))
+		return
+	}
+	defer unlock()
+
+	for _, key := range order {
+		reqs := groups[key]
+		finish(reqs, g.commit(reqs))
+	}
+}
+
+func (g *groupCommitter) commit(reqs []*commitRequest) error {
+	files := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		files = append(files, req.file)
+	}
+	if err := g.repo.git.Add(files...); err != nil {
+		return fmt.Errorf("failed to git add: %w", err)
+	}
+	if err := g.repo.git.CommitWith(combineReasons(reqs), reqs[0].opts); err != nil {
+		// Unstage, so the failed batch does not leak into the next commit.
+		_, _ = g.repo.git.Run(append([]string{"reset", "-q", "--"}, files...)...)
+		return fmt.Errorf("failed to git commit: %w", err)
+	}
+	return nil
+}
+
+func finish(reqs []*commitRequest, err error) {
+	for _, req := range reqs {
+		req.done <- err
+	}
+}
+
+// combineReasons merges the change reasons of a batch into one commit message.
+// A single request keeps its message; otherwise the subject summarizes the batch
+// and the body lists the first line of each distinct reason.
+func combineReasons(reqs []*commitRequest) string {
+	if len(reqs) == 1 {
+		return reqs[0].reason
+	}
+
+	var lines []string
+	seen := make(map[string]bool)
+	for _, req := range reqs {
+		line := strings.SplitN(req.reason, "\n", 2)[0]
+		if seen[line] {
+			continue
+		}
+		seen[line] = true
+		lines = append(lines, "- "+line)
+	}
+	return fmt.Sprintf("batch update (%d documents)\n\n%s", len(reqs), strings.Join(lines, "\n"))
+}
@@ -0,0 +1,112 @@
+package fs_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aretw0/loam/pkg/adapters/fs"
+	"github.com/aretw0/loam/pkg/core"
+	"github.com/aretw0/loam/pkg/git"
+)
+
+func TestGroupCommit(t *testing.T) {
+	if !git.IsInstalled() {
+		t.Skip("git not installed")
+	}
+
+	setup := func(t *testing.T, maxBatch int) (*fs.Repository, string, *git.Client) {
+		repo, path, client := setupRepo(t, func(c *fs.Config) {
+			c.Gitless = false
+			c.GroupCommitWindow = 100 * time.Millisecond
+			c.GroupCommitMaxBatch = maxBatch
+		})
+		if err := repo.Initialize(context.Background()); err != nil {
+			t.Fatalf("Initialize failed: %v", err)
+		}
+		return repo, path, client
+	}
+
+	countCommits := func(t *testing.T, client *git.Client) int {
+		t.Helper()
+		out, err := client.Run("rev-list", "--count", "HEAD")
+		if err != nil {
+			t.Fatalf("rev-list failed: %v", err)
+		}
+		n, _ := strconv.Atoi(out)
+		return n
+	}
+
+	saveConcurrently := func(repo *fs.Repository, n int) []error {
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = repo.Save(context.Background(), core.Document{ID: fmt.Sprintf("doc-%d", i), Content: "x"})
+			}(i)
+		}
+		wg.Wait()
+		return errs
+	}
+
+	t.Run("Coalesces Concurrent Saves", func(t *testing.T) {
+		repo, _, client := setup(t, 0)
+		before := countCommits(t, client)
+
+		for i, err := range saveConcurrently(repo, 10) {
+			if err != nil {
+				t.Errorf("save %d failed: %v", i, err)
+			}
+		}
+
+		commits := countCommits(t, client) - before
+		if commits < 1 || commits >= 10 {
+			t.Errorf("expected saves to be grouped into fewer commits, got %d", commits)
+		}
+		status, _ := client.Status()
+		if status != "" {
+			t.Errorf("expected clean tree after group commit, got:\n%s", status)
+		}
+
+		msg, _ := client.Run("log", "-1", "--pretty=%B")
+		if commits == 1 && !contains(msg, "batch update (10 documents)") {
+			t.Errorf("unexpected combined message: %q", msg)
+		}
+	})
+
+	t.Run("Flushes At Max Batch", func(t *testing.T) {
+		repo, _, client := setup(t, 2)
+		before := countCommits(t, client)
+
+		for i, err := range saveConcurrently(repo, 4) {
+			if err != nil {
+				t.Errorf("save %d failed: %v", i, err)
+			}
+		}
+		if commits := countCommits(t, client) - before; commits < 2 {
+			t.Errorf("expected at least 2 commits with max batch 2, got %d", commits)
+		}
+	})
+
+	t.Run("Propagates Commit Failure", func(t *testing.T) {
+		repo, path, _ := setup(t, 0)
+
+		hook := filepath.Join(path, ".git", "hooks", "pre-commit")
+		if err := os.WriteFile(hook, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		for i, err := range saveConcurrently(repo, 3) {
+			if err == nil {
+				t.Errorf("save %d: expected commit failure to be reported", i)
+			}
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// History implements core.Versioned.
+// It lists the git commits that touched the document's file (or its collection file, for rows),
+// newest first, with the verification status of each commit signature.
+func (r *Repository) History(ctx context.Context, id string) ([]core.Revision, error) {
+	if r.config.Gitless || !r.git.IsRepo() {
+		return nil, fmt.Errorf("history requires versioning")
+	}
+
+	filename, _ := r.resolveFilename(id)
+	if collectionPath, _, _, found := r.findCollection(id); found {
+		rel, err := filepath.Rel(r.Path, collectionPath)
+		if err != nil {
+			return nil, err
+		}
+		filename = rel
+	}
+
+	entries, err := r.git.Log(filepath.ToSlash(filename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history of %s: %w", id, err)
+	}
+
+	revisions := make([]core.Revision, 0, len(entries))
+	for _, e := range entries {
+		revisions = append(revisions, core.Revision{
+			Hash:        e.Hash,
+			Author:      e.Author,
+			AuthorEmail: e.AuthorEmail,
+			Timestamp:   e.Timestamp,
+			Message:     e.Subject,
+			Signature:   signatureStatus(e.Signature),
+			Signer:      e.Signer,
+		})
+	}
+	return revisions, nil
+}
+
+// signatureStatus maps git's %G? codes to core.SignatureStatus.
+func signatureStatus(code string) core.SignatureStatus {
+	switch code {
+	case "G":
+		return core.SignatureGood
+	case "U", "E":
+		// U: good signature with unknown validity; E: signature could not be checked
+		// (missing key, or no allowed signers for SSH).
+		return core.SignatureUntrusted
+	case "X", "Y":
+		return core.SignatureExpired
+	case "B", "R":
+		return core.SignatureBad
+	default:
+		return core.SignatureUnsigned
+	}
+}
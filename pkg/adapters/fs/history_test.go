@@ -0,0 +1,190 @@
+package fs_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aretw0/loam/pkg/adapters/fs"
+	"github.com/aretw0/loam/pkg/core"
+	"github.com/aretw0/loam/pkg/git"
+)
+
+func TestHistory_SSHSigning(t *testing.T) {
+	key := sshSigningKey(t)
+	pub, err := os.ReadFile(key + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := filepath.Join(filepath.Dir(key), "allowed_signers")
+	if err := os.WriteFile(allowed, append([]byte("signer@example.com "), pub...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, _, _ := setupRepo(t, func(c *fs.Config) {
+		c.Gitless = false
+		c.Signing = &git.Signing{Format: "ssh", Key: key, AllowedSigners: allowed}
+	})
+	ctx := context.Background()
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	if err := repo.Save(ctx, core.Document{ID: "signed", Content: "v1"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	tx, _ := repo.Begin(ctx)
+	tx.Save(ctx, core.Document{ID: "signed", Content: "v2"})
+	if err := tx.Commit(ctx, "batch update"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	revs, err := repo.History(ctx, "signed")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(revs) != 2 {
+		t.Fatalf("expected 2 revisions, got %d", len(revs))
+	}
+	if revs[0].Message != "batch update" {
+		t.Errorf("expected newest revision first, got %q", revs[0].Message)
+	}
+	for _, rev := range revs {
+		if rev.Signature != core.SignatureGood {
+			t.Errorf("revision %s: expected good signature, got %s", rev.Hash, rev.Signature)
+		}
+	}
+
+	// A key missing from the allowed signers is reported as untrusted.
+	if err := os.WriteFile(allowed, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	revs, err = repo.History(ctx, "signed")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if revs[0].Signature != core.SignatureUntrusted {
+		t.Errorf("expected untrusted signature, got %s", revs[0].Signature)
+	}
+}
+
+// sshSigningKey generates an SSH signing key, skipping the test without git or ssh-keygen.
+func sshSigningKey(t *testing.T) string {
+	t.Helper()
+	if !git.IsInstalled() {
+		t.Skip("git not installed")
+	}
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not installed")
+	}
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", "signer@example.com", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen failed: %v\n%s", err, out)
+	}
+	return key
+}
+
+func TestHistory_SSHSigningWithoutAllowedSigners(t *testing.T) {
+	key := sshSigningKey(t)
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull) // No allowedSignersFile from the user's config.
+
+	repo, _, _ := setupRepo(t, func(c *fs.Config) {
+		c.Gitless = false
+		c.Signing = &git.Signing{Format: "ssh", Key: key}
+	})
+	ctx := context.Background()
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	for _, content := range []string{"v1", "v2"} {
+		if err := repo.Save(ctx, core.Document{ID: "signed", Content: content}); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	revs, err := repo.History(ctx, "signed")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(revs) != 2 {
+		t.Fatalf("expected 2 revisions, got %d", len(revs))
+	}
+	for _, rev := range revs {
+		if len(rev.Hash) != 40 || strings.Trim(rev.Hash, "0123456789abcdef") != "" {
+			t.Errorf("stderr leaked into the hash: %q", rev.Hash)
+		}
+		// The signature exists but cannot be verified: not unsigned.
+		if rev.Signature != core.SignatureUntrusted {
+			t.Errorf("revision %s: expected untrusted signature, got %s", rev.Hash, rev.Signature)
+		}
+	}
+}
+
+func TestHistory_GPGSigning(t *testing.T) {
+	if !git.IsInstalled() {
+		t.Skip("git not installed")
+	}
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+
+	// Isolated keyring (short path: gpg-agent sockets have length limits)
+	home, err := os.MkdirTemp("", "gpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		exec.Command("gpgconf", "--homedir", home, "--kill", "gpg-agent").Run()
+		os.RemoveAll(home)
+	})
+	t.Setenv("GNUPGHOME", home)
+	if out, err := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key", "Signer <signer@example.com>", "ed25519", "sign", "never").CombinedOutput(); err != nil {
+		t.Skipf("gpg key generation unavailable: %v\n%s", err, out)
+	}
+
+	repo, _, _ := setupRepo(t, func(c *fs.Config) {
+		c.Gitless = false
+		c.Signing = &git.Signing{Format: "openpgp", Key: "signer@example.com"}
+	})
+	ctx := context.Background()
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if err := repo.Save(ctx, core.Document{ID: "signed", Content: "v1"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	revs, err := repo.History(ctx, "signed")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(revs) != 1 || revs[0].Signature != core.SignatureGood {
+		t.Errorf("expected one good revision, got %+v", revs)
+	}
+}
+
+func TestHistory_Unsigned(t *testing.T) {
+	if !git.IsInstalled() {
+		t.Skip("git not installed")
+	}
+
+	repo, _, _ := setupRepo(t, func(c *fs.Config) {
+		c.Gitless = false
+	})
+	ctx := context.Background()
+	repo.Initialize(ctx)
+
+	if err := repo.Save(ctx, core.Document{ID: "plain", Content: "v1"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	revs, err := repo.History(ctx, "plain")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(revs) != 1 || revs[0].Signature != core.SignatureUnsigned {
+		t.Errorf("expected one unsigned revision, got %+v", revs)
+	}
+}
@@ -0,0 +1,451 @@
+package fs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aretw0/loam/pkg/git"
+)
+
+const (
+	// journalDirName is the directory, inside SystemDir, holding transaction journals.
+	journalDirName = "journal"
+	// decisionDirName holds the commit decisions of two-phase commits coordinated by this vault.
+	decisionDirName = "decisions"
+)
+
+// journalState tells recovery which way to resolve an interrupted transaction.
+type journalState string
+
+const (
+	// journalCommitting: the journal holds every final write, so recovery rolls forward.
+	journalCommitting journalState = "committing"
+	// journalAborting: the transaction failed while applying and recovery rolls back.
+	journalAborting journalState = "aborting"
+	// journalPrepared: the transaction is part of a two-phase commit and nothing was
+	// applied yet. Recovery commits it if the coordinator recorded the decision.
+	journalPrepared journalState = "prepared"
+	// journalDone: the transaction was resolved but its journal could not be removed.
+	// Recovery only drops it, so it is never applied again over newer writes.
+	journalDone journalState = "done"
+)
+
+// journalOp is a single file change of a transaction.
+type journalOp struct {
+	Path    string `json:"path"` // Relative to the vault, slash-separated.
+	Data    []byte `json:"data,omitempty"`
+	Delete  bool   `json:"delete,omitempty"`
+	Existed bool   `json:"existed"`
+	// Before holds the previous content when git cannot restore it
+	// (gitless vaults, or files with uncommitted changes).
+	Before []byte `json:"before,omitempty"`
+	// Clean marks files identical to HEAD, restored from git on rollback.
+	Clean bool `json:"clean,omitempty"`
+}
+
+// journal is the write-ahead record of a transaction: it is durably written
+// before any document is touched and removed once the transaction is committed.
+type journal struct {
+	ID       string       `json:"id"`
+	State    journalState `json:"state"`
+	Created  time.Time    `json:"created"`
+	Message  string       `json:"message"`
+	Author   string       `json:"author,omitempty"`
+	Trailers []string     `json:"trailers,omitempty"`
+	Ops      []journalOp  `json:"ops"`
+
+	// Head is the commit HEAD pointed to when the journal was recorded (versioned
+	// vaults only; "" before the first commit). Recovery rolls forward only while
+	// HEAD has not moved on.
+	Head string `json:"head,omitempty"`
+
+	// Two-phase commit (prepared state only)
+	Coordinator string `json:"coordinator,omitempty"` // Global transaction ID.
+	Decision    string `json:"decision,omitempty"`    // Path of the coordinator's decision file.
+}
+
+func (r *Repository) journalDir() string {
+	return filepath.Join(r.Path, r.config.SystemDir, journalDirName)
+}
+
+func (r *Repository) journalPath(id string) string {
+	return filepath.Join(r.journalDir(), id+".json")
+}
+
+// newJournal prepares a journal for the given operations, capturing what is
+// needed to roll them back.
+func (r *Repository) newJournal(ops []journalOp, msg string, opts git.CommitOptions) (*journal, error) {
+	var dirty map[string]bool
+	var head string
+	if !r.config.Gitless {
+		paths := make([]string, len(ops))
+		for i, op := range ops {
+			paths[i] = op.Path
+		}
+		var err error
+		if dirty, err = r.dirtyPaths(paths); err != nil {
+			return nil, err
+		}
+		if head, err = r.git.Head(); err != nil {
+			return nil, fmt.Errorf("failed to read HEAD: %w", err)
+		}
+	}
+
+	for i := range ops {
+		op := &ops[i]
+		data, err := os.ReadFile(filepath.Join(r.Path, filepath.FromSlash(op.Path)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", op.Path, err)
+		}
+		op.Existed = true
+		if !r.config.Gitless && !dirty[op.Path] {
+			op.Clean = true
+			continue
+		}
+		op.Before = data
+	}
+
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	return &journal{
+		ID:       hex.EncodeToString(id[:]),
+		State:    journalCommitting,
+		Created:  time.Now(),
+		Message:  msg,
+		Author:   opts.Author,
+		Trailers: opts.Trailers,
+		Ops:      ops,
+		Head:     head,
+	}, nil
+}
+
+// dirtyPaths returns the paths that are untracked or differ from HEAD.
+func (r *Repository) dirtyPaths(paths []string) (map[string]bool, error) {
+	changed, err := r.git.ChangedPaths(paths...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check git status: %w", err)
+	}
+	dirty := make(map[string]bool, len(changed))
+	for _, path := range changed {
+		dirty[path] = true
+	}
+	return dirty, nil
+}
+
+// writeJournal durably records the journal.
+func (r *Repository) writeJournal(j *journal) error {
+	if err := os.MkdirAll(r.journalDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(r.journalPath(j.ID), data, 0644); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// removeJournal drops the journal of a resolved transaction. If the file cannot be
+// removed, the journal is marked done instead; an error means neither worked and
+// the next Initialize would resolve the transaction again.
+func (r *Repository) removeJournal(j *journal) error {
+	err := os.Remove(r.journalPath(j.ID))
+	if err == nil || os.IsNotExist(err) {
+		return nil
+	}
+	done := *j
+	done.State = journalDone
+	done.Ops = nil
+	if werr := r.writeJournal(&done); werr != nil {
+		return fmt.Errorf("failed to remove transaction journal %s: %w", j.ID, errors.Join(err, werr))
+	}
+	if r.config.Logger != nil {
+		r.config.Logger.Warn("failed to remove transaction journal, marked it done", "id", j.ID, "err", err)
+	}
+	return nil
+}
+
+// applyJournal writes the final state of every operation. It is idempotent.
+func (r *Repository) applyJournal(j *journal) error {
+	for _, op := range j.Ops {
+		fullPath := filepath.Join(r.Path, filepath.FromSlash(op.Path))
+		if op.Delete {
+			if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove file %s: %w", op.Path, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directories for %s: %w", op.Path, err)
+		}
+		if err := writeFileAtomic(fullPath, op.Data, 0644); err != nil {
+			return fmt.Errorf("failed to write file %s: %w", op.Path, err)
+		}
+	}
+	return nil
+}
+
+// commitJournal stages and commits the journal's changes. It commits nothing if
+// the changes are already in HEAD (e.g. a crash after commit, before cleanup).
+func (r *Repository) commitJournal(j *journal) error {
+	if len(j.Ops) == 0 {
+		return nil
+	}
+
+	var add, rm, all []string
+	for _, op := range j.Ops {
+		all = append(all, op.Path)
+		if op.Delete {
+			rm = append(rm, op.Path)
+		} else {
+			add = append(add, op.Path)
+		}
+	}
+
+	if err := r.git.Add(add...); err != nil {
+		return fmt.Errorf("failed to git add: %w", err)
+	}
+	if len(rm) > 0 {
+		if _, err := r.git.Run(append([]string{"rm", "-q", "--cached", "--ignore-unmatch", "--"}, rm...)...); err != nil {
+			return fmt.Errorf("failed to git rm: %w", err)
+		}
+	}
+
+	staged, err := r.git.Run(append([]string{"diff", "--cached", "--name-only", "--"}, all...)...)
+	if err != nil {
+		return fmt.Errorf("failed to inspect staged changes: %w", err)
+	}
+	if staged == "" {
+		return nil
+	}
+
+	if err := r.git.CommitWith(j.Message, git.CommitOptions{Author: j.Author, Trailers: j.Trailers}); err != nil {
+		return fmt.Errorf("failed to git commit: %w", err)
+	}
+	return nil
+}
+
+// revertJournal restores every file to its state before the transaction.
+func (r *Repository) revertJournal(j *journal) error {
+	var all, fromGit []string
+	for _, op := range j.Ops {
+		all = append(all, op.Path)
+		if op.Clean {
+			fromGit = append(fromGit, op.Path)
+		}
+	}
+
+	if !r.config.Gitless {
+		// Unstage anything added before the failure.
+		_, _ = r.git.Run(append([]string{"reset", "-q", "--"}, all...)...)
+		if len(fromGit) > 0 {
+			if _, err := r.git.Run(append([]string{"checkout", "HEAD", "--"}, fromGit...)...); err != nil {
+				return fmt.Errorf("failed to restore files from git: %w", err)
+			}
+		}
+	}
+
+	for _, op := range j.Ops {
+		if op.Clean {
+			continue
+		}
+		fullPath := filepath.Join(r.Path, filepath.FromSlash(op.Path))
+		if !op.Existed {
+			if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove file %s: %w", op.Path, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directories for %s: %w", op.Path, err)
+		}
+		if err := writeFileAtomic(fullPath, op.Before, 0644); err != nil {
+			return fmt.Errorf("failed to restore file %s: %w", op.Path, err)
+		}
+	}
+	return nil
+}
+
+// abortJournal rolls back a failed transaction, keeping the journal if rollback fails
+// so that the next Initialize can finish it.
+func (r *Repository) abortJournal(j *journal, cause error) error {
+	j.State = journalAborting
+	if err := r.writeJournal(j); err != nil {
+		return fmt.Errorf("%w (rollback not recorded: %v)", cause, err)
+	}
+	if err := r.revertJournal(j); err != nil {
+		return fmt.Errorf("%w (rollback failed, will retry on next start: %v)", cause, err)
+	}
+	if err := r.removeJournal(j); err != nil {
+		return fmt.Errorf("%w (rolled back, but %v)", cause, err)
+	}
+	return cause
+}
+
+// staleJournal reports whether a journal to roll forward must be dropped instead
+// because HEAD moved on since it was recorded: either its commit landed before the
+// crash (only the cleanup is missing), or the lock was broken and the vault written
+// since, and applying it would revert those newer writes.
+func (r *Repository) staleJournal(j *journal) (bool, error) {
+	head, err := r.git.Head()
+	if err != nil {
+		return false, err
+	}
+	if head == j.Head {
+		return false, nil
+	}
+
+	committed := false
+	if next, err := r.git.FirstChild(j.Head); err != nil {
+		return false, err
+	} else if next != "" {
+		subject, err := r.git.Run("log", "-1", "--format=%s", next)
+		if err != nil {
+			return false, err
+		}
+		first, _, _ := strings.Cut(j.Message, "\n")
+		committed = subject == strings.TrimSpace(first)
+	}
+	if r.config.Logger != nil {
+		if committed {
+			r.config.Logger.Info("interrupted transaction was already committed", "id", j.ID)
+		} else {
+			r.config.Logger.Warn("dropped interrupted transaction: the vault changed since, applying it would revert newer commits", "id", j.ID, "head", head)
+		}
+	}
+	return true, nil
+}
+
+// recoverJournals resolves transactions interrupted by a crash: journals in the
+// committing state are rolled forward (unless HEAD moved on, see staleJournal),
+// aborting ones are rolled back.
+func (r *Repository) recoverJournals(ctx context.Context) error {
+	entries, err := os.ReadDir(r.journalDir())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read journal directory: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// Live transactions hold the lock while their journal exists.
+	unlock, err := r.git.LockContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire git lock: %w", err)
+	}
+	defer unlock()
+
+	var touched []string
+	for _, entry := range entries {
+		path := filepath.Join(r.journalDir(), entry.Name())
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			// Leftover temp files from an interrupted journal write.
+			if strings.HasPrefix(entry.Name(), TempFilePrefix) {
+				os.Remove(path)
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read journal %s: %w", entry.Name(), err)
+		}
+		var j journal
+		if err := json.Unmarshal(data, &j); err != nil {
+			// Journals are written atomically; an unreadable one was never applied.
+			os.Remove(path)
+			continue
+		}
+
+		if j.State == journalPrepared {
+			_, err := os.Stat(j.Decision)
+			switch {
+			case err == nil:
+				j.State = journalCommitting
+			case os.IsNotExist(err):
+				// Presumed abort: nothing was applied, just drop the journal.
+				if r.config.Logger != nil {
+					r.config.Logger.Warn("aborted in-doubt transaction", "id", j.ID, "coordinator", j.Coordinator)
+				}
+				if err := r.removeJournal(&j); err != nil {
+					return err
+				}
+				continue
+			default:
+				return fmt.Errorf("failed to resolve in-doubt transaction %s: %w", j.ID, err)
+			}
+		}
+
+		switch j.State {
+		case journalDone:
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove transaction journal %s: %w", j.ID, err)
+			}
+			continue
+		case journalAborting:
+			if err := r.revertJournal(&j); err != nil {
+				return fmt.Errorf("failed to roll back transaction %s: %w", j.ID, err)
+			}
+		default:
+			if !r.config.Gitless {
+				stale, err := r.staleJournal(&j)
+				if err != nil {
+					return fmt.Errorf("failed to roll forward transaction %s: %w", j.ID, err)
+				}
+				if stale {
+					if err := r.removeJournal(&j); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			if err := r.applyJournal(&j); err != nil {
+				return fmt.Errorf("failed to roll forward transaction %s: %w", j.ID, err)
+			}
+			if !r.config.Gitless {
+				if err := r.commitJournal(&j); err != nil {
+					return fmt.Errorf("failed to roll forward transaction %s: %w", j.ID, err)
+				}
+			}
+		}
+
+		if r.config.Logger != nil {
+			r.config.Logger.Warn("recovered interrupted transaction", "id", j.ID, "state", j.State, "files", len(j.Ops))
+		}
+		for _, op := range j.Ops {
+			touched = append(touched, op.Path)
+		}
+		if err := r.removeJournal(&j); err != nil {
+			return err
+		}
+	}
+
+	// Drop recovered files from the index so they are re-read from disk.
+	if len(touched) > 0 && r.cache.Load() == nil {
+		for _, path := range touched {
+			r.cache.Delete(path)
+		}
+		if err := r.cache.Save(); err != nil && r.config.Logger != nil {
+			r.config.Logger.Error("failed to save cache after recovery", "err", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package fs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+func TestTransactionJournal(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	ctx := context.Background()
+	open := func(t *testing.T, path string, gitless bool) *Repository {
+		t.Helper()
+		repo := NewRepository(Config{
+			Path:      path,
+			AutoInit:  true,
+			Gitless:   gitless,
+			SystemDir: ".loam",
+			Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		})
+		if err := repo.Initialize(ctx); err != nil {
+			t.Fatalf("failed to init repo: %v", err)
+		}
+		return repo
+	}
+	read := func(t *testing.T, path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+	assertNoJournals := func(t *testing.T, repo *Repository) {
+		t.Helper()
+		entries, _ := os.ReadDir(repo.journalDir())
+		if len(entries) != 0 {
+			t.Errorf("expected journal to be cleared, found %d entries", len(entries))
+		}
+	}
+
+	t.Run("Rolls Back On Commit Failure", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		repo := open(t, tmpDir, false)
+		if err := repo.Save(ctx, core.Document{ID: "a", Content: "original"}); err != nil {
+			t.Fatalf("save failed: %v", err)
+		}
+		before := read(t, filepath.Join(tmpDir, "a.md"))
+
+		hook := filepath.Join(tmpDir, ".git", "hooks", "pre-commit")
+		if err := os.WriteFile(hook, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		tx, _ := repo.Begin(ctx)
+		tx.Save(ctx, core.Document{ID: "a", Content: "changed"})
+		tx.Save(ctx, core.Document{ID: "b", Content: "new"})
+		if err := tx.Commit(ctx, "doomed"); err == nil {
+			t.Fatal("expected commit to fail")
+		}
+
+		if got := read(t, filepath.Join(tmpDir, "a.md")); got != before {
+			t.Errorf("expected a.md to be restored, got %q", got)
+		}
+		if _, err := os.Stat(filepath.Join(tmpDir, "b.md")); !os.IsNotExist(err) {
+			t.Error("expected b.md to be removed")
+		}
+		if status, _ := repo.git.Status(); status != "" {
+			t.Errorf("expected clean tree after rollback, got:\n%s", status)
+		}
+		assertNoJournals(t, repo)
+	})
+
+	t.Run("Rolls Forward Interrupted Commit", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		repo := open(t, tmpDir, false)
+
+		// Simulate a crash after the journal was written and one file applied.
+		j, err := repo.newJournal([]journalOp{
+			{Path: "x.md", Data: []byte("x")},
+			{Path: "y.md", Data: []byte("y")},
+		}, "interrupted", commitOptions(ctx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.writeJournal(j); err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(filepath.Join(tmpDir, "x.md"), []byte("x"), 0644)
+
+		repo = open(t, tmpDir, false)
+		if read(t, filepath.Join(tmpDir, "x.md")) != "x" || read(t, filepath.Join(tmpDir, "y.md")) != "y" {
+			t.Error("expected all journaled writes to be applied")
+		}
+		if msg, _ := repo.git.Run("log", "-1", "--pretty=%s"); msg != "interrupted" {
+			t.Errorf("expected recovered commit, got %q", msg)
+		}
+		if status, _ := repo.git.Status(); status != "" {
+			t.Errorf("expected clean tree after recovery, got:\n%s", status)
+		}
+		assertNoJournals(t, repo)
+	})
+
+	t.Run("Drops Journal When HEAD Moved On", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		repo := open(t, tmpDir, false)
+		if err := repo.Save(ctx, core.Document{ID: "x", Content: "original"}); err != nil {
+			t.Fatal(err)
+		}
+
+		// A crash after the commit, before the journal was removed.
+		tx, _ := repo.Begin(ctx)
+		tx.Save(ctx, core.Document{ID: "x", Content: "committed"})
+		j, err := tx.(*Transaction).prepareLocked(ctx, "crashed")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.writeJournal(j); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.(*Transaction).apply(j); err != nil {
+			t.Fatal(err)
+		}
+
+		repo = open(t, tmpDir, false)
+		assertNoJournals(t, repo)
+		if msg, _ := repo.git.Run("log", "-1", "--pretty=%s"); msg != "crashed" {
+			t.Errorf("expected the landed commit to be kept as is, got %q", msg)
+		}
+
+		// The same crash, then the stale lock is broken and a newer write lands.
+		tx, _ = repo.Begin(ctx)
+		tx.Save(ctx, core.Document{ID: "x", Content: "old staged"})
+		j, err = tx.(*Transaction).prepareLocked(ctx, "crashed again")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.writeJournal(j); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.(*Transaction).apply(j); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Save(ctx, core.Document{ID: "x", Content: "newer"}); err != nil {
+			t.Fatal(err)
+		}
+
+		repo = open(t, tmpDir, false)
+		assertNoJournals(t, repo)
+		if doc, err := repo.Get(ctx, "x"); err != nil || doc.Content != "newer" {
+			t.Errorf("expected the newer write to survive recovery, got %q (%v)", doc.Content, err)
+		}
+		if msg, _ := repo.git.Run("log", "-1", "--pretty=%s"); msg == "crashed again" {
+			t.Error("expected the stale journal not to be committed over the newer write")
+		}
+	})
+
+	t.Run("Finishes Interrupted Rollback", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		repo := open(t, tmpDir, true)
+		target := filepath.Join(tmpDir, "doc.md")
+		os.WriteFile(target, []byte("before"), 0644)
+
+		j, err := repo.newJournal([]journalOp{
+			{Path: "doc.md", Data: []byte("after")},
+			{Path: "fresh.md", Data: []byte("fresh")},
+		}, "aborted", commitOptions(ctx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.State = journalAborting
+		if err := repo.writeJournal(j); err != nil {
+			t.Fatal(err)
+		}
+		repo.applyJournal(j)
+
+		repo = open(t, tmpDir, true)
+		if got := read(t, target); got != "before" {
+			t.Errorf("expected doc.md to be restored, got %q", got)
+		}
+		if _, err := os.Stat(filepath.Join(tmpDir, "fresh.md")); !os.IsNotExist(err) {
+			t.Error("expected fresh.md to be removed")
+		}
+		assertNoJournals(t, repo)
+	})
+
+	t.Run("Drops Done Journal", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		repo := open(t, tmpDir, true)
+		target := filepath.Join(tmpDir, "doc.md")
+
+		// A committed transaction whose journal could not be removed, then a newer write.
+		j, err := repo.newJournal([]journalOp{{Path: "doc.md", Data: []byte("old")}}, "done", commitOptions(ctx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.State = journalDone
+		if err := repo.writeJournal(j); err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(target, []byte("newer"), 0644)
+
+		repo = open(t, tmpDir, true)
+		if got := read(t, target); got != "newer" {
+			t.Errorf("done journal was re-applied: %q", got)
+		}
+		assertNoJournals(t, repo)
+	})
+
+	t.Run("Dirty Paths With Special Characters", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		repo := open(t, tmpDir, false)
+		names := []string{"notes/my doc.md", "notes/café.md", `notes/a"b.md`}
+		for _, name := range names {
+			if err := repo.Save(ctx, core.Document{ID: strings.TrimSuffix(name, ".md"), Content: "v1"}); err != nil {
+				t.Fatalf("save failed: %v", err)
+			}
+			os.WriteFile(filepath.Join(tmpDir, filepath.FromSlash(name)), []byte("edited"), 0644)
+		}
+		os.WriteFile(filepath.Join(tmpDir, "notes", "new file.md"), []byte("untracked"), 0644)
+
+		dirty, err := repo.dirtyPaths(append(names, "notes/new file.md", "notes/clean.md"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range append(names, "notes/new file.md") {
+			if !dirty[name] {
+				t.Errorf("expected %q to be dirty, got %v", name, dirty)
+			}
+		}
+		if len(dirty) != len(names)+1 {
+			t.Errorf("unexpected dirty paths: %v", dirty)
+		}
+	})
+}
@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aretw0/lifecycle"
@@ -43,6 +44,22 @@ type Repository struct {
 	// readOnly indicates if the repository is in read-only mode.
 	readOnly bool
 
+	// group coalesces concurrent saves into fewer commits (nil when disabled).
+	group *groupCommitter
+
+	// watchers tracks active watch sources so that changes applied by this process
+	// outside of fsnotify (e.g. workspace merges) can be delivered to them.
+	watchers sync.Map // *directoryWatchSource -> struct{}
+
+	// changes is the durable change log (used when Config.ChangeLog is set).
+	changes *changeLog
+	// trackMu serializes recording external changes in the index and the change log.
+	trackMu sync.Mutex
+
+	// pauseWatch suspends watcher processing while this process rewrites the tree
+	// through git (e.g. workspace merges). Counter, so operations may nest.
+	pauseWatch atomic.Int32
+
 	// Observability fields (protected by mu)
 	mu            sync.RWMutex
 	watcherActive bool
@@ -64,6 +81,29 @@ type Config struct {
 	MarkdownBodyKey   string            // Key used to store Markdown body when ContentExtraction is false.
 	ErrorHandler      func(error)       // Optional callback for handling runtime watcher errors.
 	ReadOnly          bool              // If true, disables all write operations.
+	Signing           *git.Signing      // Optional. Signs every commit (SSH/GPG) and verifies signatures in History.
+
+	// Polling watches by periodically reconciling the vault against the index instead of
+	// relying on fsnotify, for filesystems without change notifications (NFS, SMB, some
+	// container volumes). Watch also falls back to polling when fsnotify cannot start.
+	Polling bool
+	// PollInterval is the polling period. Zero means DefaultPollInterval.
+	PollInterval time.Duration
+
+	// GroupCommitWindow enables group commit: saves arriving within this window are
+	// committed together in a single git commit. Zero disables grouping.
+	GroupCommitWindow time.Duration
+	// GroupCommitMaxBatch flushes a group early once this many saves are pending. Zero means no limit.
+	GroupCommitMaxBatch int
+
+	// RichEvents attaches the document, its previous version (from the index), a metadata
+	// diff, the originating commit and the origin (local or external) to watch events.
+	// Changes made through this repository are then delivered as local events instead of
+	// being suppressed.
+	RichEvents bool
+	// ChangeLog keeps a durable log of applied changes in SystemDir, each with a
+	// sequence number, for Changes and WatchFrom.
+	ChangeLog bool
 }
 
 // NewRepository creates a new filesystem-backed repository.
@@ -84,14 +124,22 @@ func NewRepository(config Config) *Repository {
 		config.MarkdownBodyKey = "body"
 	}
 
-	return &Repository{
+	client := git.NewClient(config.Path, config.SystemDir+".lock", config.Logger)
+	client.Signing = config.Signing
+
+	r := &Repository{
 		Path:        config.Path,
-		git:         git.NewClient(config.Path, config.SystemDir+".lock", config.Logger),
+		git:         client,
 		config:      config,
 		cache:       newCache(config.Path, config.SystemDir),
+		changes:     newChangeLog(config.Path, config.SystemDir),
 		serializers: DefaultSerializers(config.Strict),
 		readOnly:    config.ReadOnly,
 	}
+	if config.GroupCommitWindow > 0 {
+		r.group = newGroupCommitter(r, config.GroupCommitWindow, config.GroupCommitMaxBatch)
+	}
+	return r
 }
 
 // RegisterSerializer adds or overrides a serializer for a specific extension.
@@ -110,12 +158,26 @@ func (r *Repository) Begin(ctx context.Context) (core.Transaction, error) {
 	return NewTransaction(r), nil
 }
 
-// Initialize performs the necessary setup for the repository (mkdir, git init).
+// Initialize performs the necessary setup for the repository (mkdir, git init)
+// and recovers transactions interrupted by a crash.
 func (r *Repository) Initialize(ctx context.Context) error {
 	if err := r.initDir(); err != nil {
 		return err
 	}
-	return r.initGit()
+	if err := r.initGit(); err != nil {
+		return err
+	}
+	if r.reportsLocal() {
+		// Previous versions of rich events come from the index, which also tells
+		// which changes are already logged.
+		if err := r.cache.Load(); err != nil && r.config.Logger != nil {
+			r.config.Logger.Warn("failed to load cache", "err", err)
+		}
+	}
+	if r.config.ReadOnly {
+		return nil
+	}
+	return r.recoverJournals(ctx)
 }
 
 func (r *Repository) initDir() error {
@@ -245,7 +307,7 @@ func (r *Repository) Sync(ctx context.Context) error {
 		return fmt.Errorf("path is not a git repository: %s", r.Path)
 	}
 
-	unlock, err := r.git.Lock()
+	unlock, err := r.git.LockContext(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to acquire git lock: %w", err)
 	}
@@ -257,11 +319,14 @@ func (r *Repository) Sync(ctx context.Context) error {
 // Watch implements core.Watchable.
 //
 // Caveats:
-//  1. Recursive Monitoring on Linux (inotify): New directories created AFTER the watch starts
-//     might NOT be monitored automatically depending on the fsnotify implementation and OS limits.
-//     Loam currently does not implement dynamic hierarchical watching for inotify.
-//  2. OS Limits: Large repositories may hit file descriptor limits (inotify limits).
+//  1. Recursive Monitoring: directories created after the watch starts are watched as they
+//     appear, and files already inside them are reported as CREATE events.
+//  2. OS Limits: Large repositories may hit inotify watch limits. When that happens the
+//     error (ErrWatchLimit) is reported through Config.ErrorHandler and the remaining
+//     directories are not watched.
 //  3. Debouncing: Events are debounced by 50ms. Rapid atomic writes (Create+Modify) are merged.
+//  4. Polling: With Config.Polling (or when fsnotify cannot start), changes are detected every
+//     PollInterval by reconciling against the index, and may be reported up to one interval late.
 func (r *Repository) Watch(ctx context.Context, pattern string) (<-chan core.Event, error) {
 	out := make(chan core.Event, 100)
 
@@ -295,6 +360,11 @@ func (r *Repository) buildDebounceMiddleware(out chan<- core.Event) events.Handl
 				if old.Type == core.EventCreate && ev.Type == core.EventModify {
 					ev.Type = core.EventCreate
 				}
+				if old.Origin != "" {
+					// Rich events: the merged event spans both changes.
+					ev.Previous = old.Previous
+					ev.Diff = core.DiffMetadata(metadataOf(ev.Previous), metadataOf(ev.Document))
+				}
 			}
 			merged.Events[ev.ID] = ev
 		}
@@ -376,31 +446,26 @@ func (r *Repository) startWatcherSupervisor(ctx context.Context, source *directo
 	return nil
 }
 
-// recursiveAdd adds the root path and all subdirectories to the watcher.
-func (r *Repository) recursiveAdd(watcher *fsnotify.Watcher) error {
-	return filepath.WalkDir(r.Path, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			name := d.Name()
-			// Skip .git and the configured SystemDir (e.g. .loam)
-			if name == ".git" || name == r.config.SystemDir {
-				return filepath.SkipDir
-			}
-			return watcher.Add(path)
-		}
-		return nil
-	})
-}
-
 // Reconcile implements core.Reconcilable.
 // It detects changes made while the service was offline by comparing the current state with the persistent cache/index.
 func (r *Repository) Reconcile(ctx context.Context) ([]core.Event, error) {
+	events, _, err := r.reconcile(ctx, core.OriginExternal, true)
+	return events, err
+}
+
+// reconcile detects changes against the index, attributing them to origin, and
+// records them in the change log. It returns the file of each event.
+// With reload, the index is first re-read from disk.
+func (r *Repository) reconcile(ctx context.Context, origin core.EventOrigin, reload bool) ([]core.Event, []string, error) {
+	r.trackMu.Lock()
+	defer r.trackMu.Unlock()
+
 	// 1. Load Cache
-	if err := r.cache.Load(); err != nil {
-		if r.config.Logger != nil {
-			r.config.Logger.Warn("failed to load cache for reconciliation", "err", err)
+	if reload {
+		if err := r.cache.Load(); err != nil {
+			if r.config.Logger != nil {
+				r.config.Logger.Warn("failed to load cache for reconciliation", "err", err)
+			}
 		}
 	}
 
@@ -408,14 +473,15 @@ func (r *Repository) Reconcile(ctx context.Context) ([]core.Event, error) {
 	visited := r.buildVisitedMap()
 
 	// 3. Walk Filesystem (Detect Creates & Modifies)
-	events, dirty, err := r.scanFileSystemForChanges(ctx, visited)
+	events, paths, dirty, err := r.scanFileSystemForChanges(ctx, visited, origin)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// 4. Detect Deletions (Unvisited Cache Entries)
-	deletedEvents, deletedDirty := r.detectDeletions(visited)
+	deletedEvents, deletedPaths, deletedDirty := r.detectDeletions(visited, origin)
 	events = append(events, deletedEvents...)
+	paths = append(paths, deletedPaths...)
 	dirty = dirty || deletedDirty
 
 	// 5. Persist Cache Updates
@@ -423,10 +489,16 @@ func (r *Repository) Reconcile(ctx context.Context) ([]core.Event, error) {
 		r.persistCacheUpdates()
 	}
 
+	// 6. Record in the change log (changes missing from the index are not logged yet)
+	for i := range events {
+		events[i].Path = paths[i]
+	}
+	r.logChanges(events, paths)
+
 	// Record reconcile completion for observability
 	r.recordReconcile()
 
-	return events, nil
+	return events, paths, nil
 }
 
 func (r *Repository) buildVisitedMap() map[string]bool {
@@ -438,8 +510,9 @@ func (r *Repository) buildVisitedMap() map[string]bool {
 	return visited
 }
 
-func (r *Repository) scanFileSystemForChanges(ctx context.Context, visited map[string]bool) ([]core.Event, bool, error) {
+func (r *Repository) scanFileSystemForChanges(ctx context.Context, visited map[string]bool, origin core.EventOrigin) ([]core.Event, []string, bool, error) {
 	var events []core.Event
+	var paths []string
 	dirty := false
 
 	err := filepath.WalkDir(r.Path, func(path string, d os.DirEntry, err error) error {
@@ -503,11 +576,12 @@ func (r *Repository) scanFileSystemForChanges(ctx context.Context, visited map[s
 			eventType = core.EventModify
 		}
 
-		events = append(events, core.Event{
+		evt := core.Event{
 			Type:      eventType,
 			ID:        id,
 			Timestamp: mtime.Unix(),
-		})
+		}
+		prev := r.cache.Peek(relPath)
 
 		// Update Cache
 		// We must parse the document to update metadata (Title/Tags) so the cache is fresh.
@@ -523,14 +597,27 @@ func (r *Repository) scanFileSystemForChanges(ctx context.Context, visited map[s
 			dirty = true
 		}
 
+		if r.config.RichEvents {
+			var current *core.Document
+			if err == nil {
+				current = &doc
+			}
+			r.enrichEvent(&evt, relPath, prev, current, origin)
+		} else {
+			evt.Origin = origin
+		}
+		events = append(events, evt)
+		paths = append(paths, relPath)
+
 		return nil
 	})
 
-	return events, dirty, err
+	return events, paths, dirty, err
 }
 
-func (r *Repository) detectDeletions(visited map[string]bool) ([]core.Event, bool) {
+func (r *Repository) detectDeletions(visited map[string]bool, origin core.EventOrigin) ([]core.Event, []string, bool) {
 	var events []core.Event
+	var paths []string
 	dirty := false
 	for relPath, foundOnDisk := range visited {
 		if !foundOnDisk {
@@ -538,11 +625,9 @@ func (r *Repository) detectDeletions(visited map[string]bool) ([]core.Event, boo
 			// We retrieve the ID from the cache directly to support all file types (json, csv)
 			// instead of blindly trimming .md.
 			id := relPath
-			// The explicit cast/access to private fields works because we are in package fs.
-			if r.cache.index != nil {
-				if entry, ok := r.cache.index.Entries[relPath]; ok {
-					id = entry.ID
-				}
+			prev := r.cache.Peek(relPath)
+			if prev != nil {
+				id = prev.ID
 			}
 			// Fallback (should be unreachable if cache is consistent)
 			if id == relPath {
@@ -550,17 +635,24 @@ func (r *Repository) detectDeletions(visited map[string]bool) ([]core.Event, boo
 				id = strings.TrimSuffix(id, ext)
 			}
 
-			events = append(events, core.Event{
+			evt := core.Event{
 				Type:      core.EventDelete,
 				ID:        id,
 				Timestamp: time.Now().Unix(),
-			})
+			}
+			if r.config.RichEvents {
+				r.enrichEvent(&evt, relPath, prev, nil, origin)
+			} else {
+				evt.Origin = origin
+			}
+			events = append(events, evt)
+			paths = append(paths, relPath)
 			// Remove from cache
 			r.cache.Delete(relPath)
 			dirty = true
 		}
 	}
-	return events, dirty
+	return events, paths, dirty
 }
 
 func (r *Repository) persistCacheUpdates() {
@@ -580,67 +672,77 @@ func (r *Repository) persistCacheUpdates() {
 	}
 }
 
-// shouldIgnore checks if the event should be filtered out.
-func (r *Repository) shouldIgnore(event fsnotify.Event, pattern string) bool {
-	// 1. Filter temp files and hidden files
-	baseName := filepath.Base(event.Name)
+// isInternalPath reports whether a path is never a document: temp and hidden files,
+// git internals and the system directory.
+func (r *Repository) isInternalPath(absPath string) bool {
+	baseName := filepath.Base(absPath)
 	if strings.HasPrefix(baseName, TempFilePrefix) || strings.HasPrefix(baseName, ".") {
 		return true
 	}
-
-	// 2. Check Pattern (Glob)
-	if pattern != "" && pattern != "*" {
-		relName, err := filepath.Rel(r.Path, event.Name)
-		if err == nil {
-			relName = filepath.ToSlash(relName)
-			matched, err := doublestar.Match(pattern, relName)
-			if err != nil {
-				if r.config.Logger != nil {
-					r.config.Logger.Error("glob match error", "err", err)
-				}
-			}
-			if !matched {
-				return true
-			}
+	if relName, err := filepath.Rel(r.Path, absPath); err == nil {
+		// Git internals (e.g. .git/MERGE_MSG) and the system directory are never documents.
+		top := strings.SplitN(filepath.ToSlash(relName), "/", 2)[0]
+		if top == ".git" || top == r.config.SystemDir {
+			return true
 		}
 	}
+	return false
+}
 
-	// 3. Check Ignore Map (Self-Modification)
-	if val, ok := r.ignoreMap.Load(event.Name); ok {
-		// Track Write events (echoes). For deletion/rename, presence in map defaults to ignore.
+// isOwnWrite reports whether the event echoes a write made by this process (see ignoreWrite).
+// The mark is kept until it expires, so every watcher recognizes the echo.
+func (r *Repository) isOwnWrite(event fsnotify.Event) bool {
+	val, ok := r.ignoreMap.Load(event.Name)
+	if !ok {
+		return false
+	}
+	// For non-write events (e.g. Remove/Rename/Chmod), trust the map presence.
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+		return true
+	}
+	expectedHash, _ := val.(string)
 
-		if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
-			expectedHash, ok := val.(string)
-			if ok {
-				// Read file to verify hash
-				content, err := os.ReadFile(event.Name)
-				if err == nil {
-					currentHash := sha256.Sum256(content)
-					currentHashStr := hex.EncodeToString(currentHash[:])
+	// Read file to verify hash
+	content, err := os.ReadFile(event.Name)
+	if err != nil {
+		if r.config.Logger != nil {
+			r.config.Logger.Debug("failed to verify ignore hash", "path", event.Name, "err", err)
+		}
+		// Conservative loop-prevention fallback.
+		return true
+	}
+	currentHash := sha256.Sum256(content)
+	// If hash mismatch, it might be a subsequent external write!
+	return hex.EncodeToString(currentHash[:]) == expectedHash
+}
 
-					if currentHashStr == expectedHash {
-						// It matches! It is our write.
-						// Remove from map (job done) and Ignore.
-						r.ignoreMap.Delete(event.Name)
-						return true
-					}
-					// If hash mismatch, it might be a subsequent external write!
-					// Do NOT ignore.
-				} else {
-					if r.config.Logger != nil {
-						r.config.Logger.Debug("failed to verify ignore hash", "path", event.Name, "err", err)
-					}
-					// Conservative loop-prevention fallback.
-					return true
-				}
-			}
-		} else {
-			// For non-write events (e.g. Rename/Chmod), trust the map presence.
-			return true
+// matchPattern reports whether the relative path matches the watch pattern.
+func (r *Repository) matchPattern(pattern, relName string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	matched, err := doublestar.Match(pattern, relName)
+	if err != nil {
+		if r.config.Logger != nil {
+			r.config.Logger.Error("glob match error", "err", err)
 		}
 	}
+	return matched
+}
 
-	return false
+// broadcast delivers events produced by this process to all active watchers.
+// relPaths holds the file path of each event and is used for pattern matching.
+func (r *Repository) broadcast(ctx context.Context, evts []core.Event, relPaths []string) {
+	r.watchers.Range(func(key, _ any) bool {
+		source := key.(*directoryWatchSource)
+		for i, e := range evts {
+			if r.matchPattern(source.pattern, relPaths[i]) {
+				e.Path = relPaths[i]
+				source.Emit(ctx, e)
+			}
+		}
+		return true
+	})
 }
 
 // mapEventType converts fsnotify.Op to core.EventType.
@@ -704,20 +806,44 @@ func (r *Repository) Save(ctx context.Context, doc core.Document) error {
 	// Ensure parent directory exists
 	// But first, check if we should intercept for Multi-Doc (Collection)
 	if collectionPath, colExt, key, found := r.findCollection(doc.ID); found {
-		return r.saveToCollection(doc, collectionPath, colExt, key)
+		rowID := r.rowID(collectionPath, key)
+		var prev *indexEntry
+		eventType := core.EventCreate
+		if r.reportsLocal() {
+			if old, err := r.getFromCollection(doc.ID); err == nil {
+				prev = &indexEntry{ID: rowID, Metadata: old.Metadata}
+				eventType = core.EventModify
+			}
+		}
+		if err := r.saveToCollection(doc, collectionPath, colExt, key); err != nil {
+			return err
+		}
+		row := doc
+		row.ID = rowID
+		r.publishLocal(ctx, core.Event{Type: eventType, ID: rowID, Timestamp: time.Now().Unix()}, rowID, prev, &row)
+		return nil
+	}
+
+	relPath := filepath.ToSlash(filename)
+	prev := r.cache.Peek(relPath)
+	eventType := core.EventModify
+	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+		eventType = core.EventCreate
 	}
 
 	if err := r.serializeAndWriteAtomic(doc, ext, fullPath); err != nil {
 		return err
 	}
 
+	// Update Cache (Optimistic). Done before committing, so a reconcile triggered
+	// by our own commit does not report the change again.
+	r.optimisticCacheUpdate(doc, fullPath)
+
 	if err := r.commitToGit(ctx, doc.ID, filename); err != nil {
 		return err
 	}
 
-	// Update Cache (Optimistic)
-	r.optimisticCacheUpdate(doc, fullPath)
-
+	r.publishLocal(ctx, core.Event{Type: eventType, ID: doc.ID, Timestamp: time.Now().Unix()}, relPath, prev, &doc)
 	return nil
 }
 
@@ -757,27 +883,45 @@ func (r *Repository) serializeAndWriteAtomic(doc core.Document, ext, fullPath st
 		return fmt.Errorf("failed to serialize document: %w", err)
 	}
 
+	r.ignoreWrite(fullPath, data)
+
+	if err := writeFileAtomic(fullPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// ignoreWrite marks a write of data to fullPath as our own, so the watcher does not report it.
+// A nil data marks a removal.
+func (r *Repository) ignoreWrite(fullPath string, data []byte) {
 	// Robust Ignore: Store content hash instead of just timestamp.
 	// We calculate hash of data about to be written.
-	hash := sha256.Sum256(data)
-	hashStr := hex.EncodeToString(hash[:])
+	hashStr := ""
+	if data != nil {
+		hash := sha256.Sum256(data)
+		hashStr = hex.EncodeToString(hash[:])
+	}
 
 	// Store in ignoreMap with expiration
 	r.ignoreMap.Store(fullPath, hashStr)
-	// Clean up after window (safety net, though successful ignore will delete it earlier)
+	// Clean up after window; every watcher checks the mark until then.
 	time.AfterFunc(2*time.Second, func() {
 		r.ignoreMap.Delete(fullPath)
 	})
-
-	if err := writeFileAtomic(fullPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
-	return nil
 }
 
 func (r *Repository) commitToGit(ctx context.Context, docID, filename string) error {
 	if !r.config.Gitless && r.git.IsRepo() {
-		unlock, err := r.git.Lock()
+		msg := "update " + docID
+		if val, ok := ctx.Value(core.ChangeReasonKey).(string); ok && val != "" {
+			msg = val
+		}
+
+		if r.group != nil {
+			return r.group.submit(ctx, filename, msg, commitOptions(ctx))
+		}
+
+		unlock, err := r.git.LockContext(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to acquire git lock: %w", err)
 		}
@@ -787,18 +931,25 @@ func (r *Repository) commitToGit(ctx context.Context, docID, filename string) er
 			return fmt.Errorf("failed to git add: %w", err)
 		}
 
-		msg := "update " + docID
-		if val, ok := ctx.Value(core.ChangeReasonKey).(string); ok && val != "" {
-			msg = val
-		}
-
-		if err := r.git.Commit(msg); err != nil {
+		if err := r.git.CommitWith(msg, commitOptions(ctx)); err != nil {
 			return fmt.Errorf("failed to git commit: %w", err)
 		}
 	}
 	return nil
 }
 
+// commitOptions builds git commit options from the ChangeInfo carried by the context.
+func commitOptions(ctx context.Context) git.CommitOptions {
+	info, ok := ctx.Value(core.ChangeInfoKey).(core.ChangeInfo)
+	if !ok {
+		return git.CommitOptions{}
+	}
+	return git.CommitOptions{
+		Author:   info.Author(),
+		Trailers: info.TrailerLines(),
+	}
+}
+
 func (r *Repository) optimisticCacheUpdate(doc core.Document, fullPath string) {
 	if info, err := os.Stat(fullPath); err == nil {
 		// Extract Generic Metadata from doc
@@ -828,29 +979,7 @@ func (r *Repository) Get(ctx context.Context, id string) (core.Document, error)
 	}
 
 	// If not in a collection, proceed as a regular file.
-	filename := id
-	ext := filepath.Ext(id)
-
-	if ext == "" {
-		// Smart Retrieval: Scan for supported extensions
-		// Priority: .md > .json > .yaml > .yml > .csv
-		extensions := []string{".md", ".json", ".yaml", ".yml", ".csv"}
-		found := false
-		for _, e := range extensions {
-			candidate := id + e
-			if _, err := os.Stat(filepath.Join(r.Path, candidate)); err == nil {
-				ext = e
-				filename = candidate
-				found = true
-				break
-			}
-		}
-		// Default to .md if none found (preserves "file not found" error for .md)
-		if !found {
-			ext = ".md"
-			filename = id + ext
-		}
-	}
+	filename, ext := r.resolveFilename(id)
 
 	fullPath := filepath.Join(r.Path, filename)
 
@@ -878,6 +1007,34 @@ func (r *Repository) Get(ctx context.Context, id string) (core.Document, error)
 	return *doc, nil
 }
 
+// resolveFilename maps a document ID to its file (relative to the vault) and extension.
+func (r *Repository) resolveFilename(id string) (filename, ext string) {
+	filename = id
+	ext = filepath.Ext(id)
+
+	if ext == "" {
+		// Smart Retrieval: Scan for supported extensions
+		// Priority: .md > .json > .yaml > .yml > .csv
+		extensions := []string{".md", ".json", ".yaml", ".yml", ".csv"}
+		found := false
+		for _, e := range extensions {
+			candidate := id + e
+			if _, err := os.Stat(filepath.Join(r.Path, candidate)); err == nil {
+				ext = e
+				filename = candidate
+				found = true
+				break
+			}
+		}
+		// Default to .md if none found (preserves "file not found" error for .md)
+		if !found {
+			ext = ".md"
+			filename = id + ext
+		}
+	}
+	return filename, ext
+}
+
 func (r *Repository) findCollection(id string) (collectionPath, collectionExt, key string, found bool) {
 	parts := strings.SplitN(id, "/", 2)
 	if len(parts) < 2 {
@@ -1077,6 +1234,7 @@ func (r *Repository) saveToCollection(doc core.Document, collectionPath, collect
 		w.Flush()
 
 		// Atomic Write
+		r.ignoreWrite(collectionPath, buf.Bytes())
 		return writeFileAtomic(collectionPath, buf.Bytes(), 0644)
 	}
 
@@ -1194,23 +1352,24 @@ func (r *Repository) flattenCollection(fullPath, relPath string) ([]core.Documen
 	return docs, nil
 }
 
-// saveBatchToCollection writes multiple documents to a collection file in one go.
+// renderCollectionBatch applies multiple documents to a collection file in one go,
+// returning the new file content without writing it.
 // Note: context is not passed here as these are blocking local file operations.
-func (r *Repository) saveBatchToCollection(collectionPath, collectionExt string, batch map[string]core.Document) error {
+func (r *Repository) renderCollectionBatch(collectionPath, collectionExt string, batch map[string]core.Document) ([]byte, error) {
 	data, err := os.ReadFile(collectionPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if collectionExt == ".csv" {
 		reader := csv.NewReader(bytes.NewReader(data))
 		allRecords, err := reader.ReadAll()
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if len(allRecords) == 0 {
-			return fmt.Errorf("empty csv collection")
+			return nil, fmt.Errorf("empty csv collection")
 		}
 
 		headers := allRecords[0]
@@ -1223,7 +1382,7 @@ func (r *Repository) saveBatchToCollection(collectionPath, collectionExt string,
 			}
 		}
 		if idCol == -1 {
-			return fmt.Errorf("csv collection missing '%s' column", idColName)
+			return nil, fmt.Errorf("csv collection missing '%s' column", idColName)
 		}
 
 		// Update rows in place
@@ -1266,15 +1425,14 @@ func (r *Repository) saveBatchToCollection(collectionPath, collectionExt string,
 		var buf bytes.Buffer
 		w := csv.NewWriter(&buf)
 		if err := w.WriteAll(allRecords); err != nil {
-			return err
+			return nil, err
 		}
 		w.Flush()
 
-		// Atomic Write
-		return writeFileAtomic(collectionPath, buf.Bytes(), 0644)
+		return buf.Bytes(), nil
 	}
 
-	return fmt.Errorf("unsupported collection type for save")
+	return nil, fmt.Errorf("unsupported collection type for save")
 }
 
 func (r *Repository) getIDColumn(filename string) string {
@@ -1303,14 +1461,24 @@ func (r *Repository) Delete(ctx context.Context, id string) error {
 		return fmt.Errorf("document not found")
 	}
 
+	relPath := filepath.ToSlash(filename)
+	var prev *indexEntry
+	if r.reportsLocal() {
+		// Reported as a local change below instead of by the watcher. The index
+		// entry is only dropped once the delete succeeded.
+		prev = r.cache.Peek(relPath)
+		r.ignoreWrite(fullPath, nil)
+	}
+
 	if r.config.Gitless {
 		if err := os.Remove(fullPath); err != nil {
 			return fmt.Errorf("failed to remove file: %w", err)
 		}
+		r.publishDelete(ctx, id, relPath, prev)
 		return nil
 	}
 
-	unlock, err := r.git.Lock()
+	unlock, err := r.git.LockContext(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to acquire git lock: %w", err)
 	}
@@ -1320,13 +1488,30 @@ func (r *Repository) Delete(ctx context.Context, id string) error {
 		return fmt.Errorf("failed to git rm: %w", err)
 	}
 
-	if err := r.git.Commit("delete " + id); err != nil {
+	msg := "delete " + id
+	if val, ok := ctx.Value(core.ChangeReasonKey).(string); ok && val != "" {
+		msg = val
+	}
+
+	if err := r.git.CommitWith(msg, commitOptions(ctx)); err != nil {
 		return fmt.Errorf("failed to git commit: %w", err)
 	}
 
+	r.publishDelete(ctx, id, relPath, prev)
 	return nil
 }
 
+// publishDelete drops a deleted document from the index and reports the delete
+// as a local change.
+func (r *Repository) publishDelete(ctx context.Context, id, relPath string, prev *indexEntry) {
+	if !r.reportsLocal() {
+		return
+	}
+	r.cache.Delete(relPath)
+	r.persistCacheUpdates()
+	r.publishLocal(ctx, core.Event{Type: core.EventDelete, ID: id, Timestamp: time.Now().Unix()}, relPath, prev, nil)
+}
+
 // IsGitInstalled checks if git is available in the system path.
 func IsGitInstalled() bool {
 	return git.IsInstalled()
@@ -163,6 +163,55 @@ func TestSave(t *testing.T) {
 		}
 
 	})
+
+	t.Run("Applies ChangeInfo Attribution", func(t *testing.T) {
+		if !git.IsInstalled() {
+			t.Skip("git not installed")
+		}
+
+		repo, _, client := setupRepo(t, func(c *fs.Config) {
+			c.Gitless = false
+		})
+		repo.Initialize(context.Background())
+
+		ctx := context.WithValue(context.Background(), core.ChangeInfoKey, core.ChangeInfo{
+			AuthorName:  "Jane Doe",
+			AuthorEmail: "jane@example.com",
+			CoAuthors:   []string{"Bob <bob@example.com>"},
+			Trailers:    map[string]string{"Ticket": "OPS-42"},
+		})
+
+		if err := repo.Save(ctx, core.Document{ID: "attributed", Content: "x"}); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		author, err := client.Run("log", "-1", "--pretty=%an <%ae>")
+		if err != nil {
+			t.Fatalf("git log failed: %v", err)
+		}
+		if author != "Jane Doe <jane@example.com>" {
+			t.Errorf("Unexpected author: %q", author)
+		}
+
+		trailers, err := client.Run("log", "-1", "--pretty=%(trailers:only)")
+		if err != nil {
+			t.Fatalf("git log failed: %v", err)
+		}
+		if !contains(trailers, "Ticket: OPS-42") || !contains(trailers, "Co-authored-by: Bob <bob@example.com>") {
+			t.Errorf("Missing trailers: %q", trailers)
+		}
+
+		// Transactions apply the same attribution
+		tx, _ := repo.Begin(ctx)
+		tx.Save(ctx, core.Document{ID: "attributed-tx", Content: "y"})
+		if err := tx.Commit(ctx, "batch"); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+		author, _ = client.Run("log", "-1", "--pretty=%an")
+		if author != "Jane Doe" {
+			t.Errorf("Unexpected transaction author: %q", author)
+		}
+	})
 }
 
 func TestGet(t *testing.T) {
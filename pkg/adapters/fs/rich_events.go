@@ -0,0 +1,119 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// enrichEvent fills the rich payload of an event (see Config.RichEvents).
+// prev is the index entry before the change and doc the new version (nil for deletes).
+func (r *Repository) enrichEvent(e *core.Event, relPath string, prev *indexEntry, doc *core.Document, origin core.EventOrigin) {
+	e.Origin = origin
+	e.Document = doc
+
+	var before, after core.Metadata
+	if prev != nil {
+		before = prev.Metadata
+		e.Previous = &core.Document{ID: prev.ID, Metadata: prev.Metadata}
+	}
+	if doc != nil {
+		after = doc.Metadata
+	}
+	e.Diff = core.DiffMetadata(before, after)
+	e.Commit = r.lastCommit(relPath)
+}
+
+// reportsLocal reports whether changes made by this process are reported, as rich
+// events or change log records, instead of only being suppressed from watchers.
+func (r *Repository) reportsLocal() bool {
+	return r.config.RichEvents || r.config.ChangeLog
+}
+
+// observeFromDisk records a change observed on disk (by the watcher): the new version
+// is read back and stored in the index, so the next event sees it as the previous one,
+// and the change is logged unless the index shows it was already recorded (e.g. by
+// another watcher or by this process).
+func (r *Repository) observeFromDisk(ctx context.Context, e *core.Event, relPath string, origin core.EventOrigin) {
+	r.trackMu.Lock()
+	defer r.trackMu.Unlock()
+
+	prev := r.cache.Peek(relPath)
+	recorded := false
+
+	var doc *core.Document
+	if e.Type == core.EventDelete {
+		recorded = prev == nil
+		r.cache.Delete(relPath)
+	} else {
+		info, statErr := os.Stat(filepath.Join(r.Path, filepath.FromSlash(relPath)))
+		recorded = statErr == nil && prev != nil && prev.LastModified.Equal(info.ModTime())
+		if d, err := r.Get(ctx, e.ID); err == nil {
+			doc = &d
+			if statErr == nil && !recorded {
+				r.cache.Set(relPath, &indexEntry{ID: e.ID, Metadata: d.Metadata, LastModified: info.ModTime()})
+			}
+		}
+	}
+
+	if r.config.RichEvents {
+		r.enrichEvent(e, relPath, prev, doc, origin)
+	} else {
+		e.Origin = origin
+	}
+	if !recorded {
+		evts := []core.Event{*e}
+		r.logChanges(evts, []string{relPath})
+		e.Cursor = evts[0].Cursor
+		// Persisted, so the next reconciliation does not report the change again.
+		r.persistCacheUpdates()
+	}
+}
+
+// publishLocal reports a change made by this process: it is logged and, with rich
+// events, enriched and delivered to all watchers.
+func (r *Repository) publishLocal(ctx context.Context, e core.Event, relPath string, prev *indexEntry, doc *core.Document) {
+	if !r.reportsLocal() {
+		return
+	}
+	e.Path = relPath
+	if r.config.RichEvents {
+		r.enrichEvent(&e, relPath, prev, doc, core.OriginLocal)
+	} else {
+		e.Origin = core.OriginLocal
+		e.Commit = r.lastCommit(relPath)
+	}
+
+	evts := []core.Event{e}
+	r.logChanges(evts, []string{relPath})
+	if r.config.RichEvents {
+		r.broadcast(ctx, evts, []string{relPath})
+	}
+}
+
+// lastCommit returns the last commit touching relPath, or "" if the file has
+// uncommitted changes (the change did not come from a commit).
+func (r *Repository) lastCommit(relPath string) string {
+	if r.config.Gitless || !r.git.IsRepo() {
+		return ""
+	}
+	relPath = collectionOf(relPath) // Rows are committed with their collection file.
+	if dirty, err := r.dirtyPaths([]string{relPath}); err != nil || dirty[relPath] {
+		return ""
+	}
+	out, err := r.git.Run("log", "-1", "--format=%H", "--", relPath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+func metadataOf(doc *core.Document) core.Metadata {
+	if doc == nil {
+		return nil
+	}
+	return doc.Metadata
+}
@@ -0,0 +1,165 @@
+package fs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+func TestRichEvents(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	tmpDir := t.TempDir()
+	repo := NewRepository(Config{
+		Path:       tmpDir,
+		AutoInit:   true,
+		SystemDir:  ".loam",
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		RichEvents: true,
+	})
+	ctx := context.Background()
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	if err := repo.Save(ctx, core.Document{ID: "note", Content: "v1", Metadata: core.Metadata{"status": "draft", "tag": "a"}}); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	events, err := repo.Watch(watchCtx, "**/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	next := func() core.Event {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e
+		case <-watchCtx.Done():
+			t.Fatal("timed out waiting for event")
+		}
+		return core.Event{}
+	}
+
+	t.Run("Local Save", func(t *testing.T) {
+		if err := repo.Save(ctx, core.Document{ID: "note", Content: "v2", Metadata: core.Metadata{"status": "published", "tag": "a"}}); err != nil {
+			t.Fatalf("failed to save: %v", err)
+		}
+
+		e := next()
+		if e.ID != "note" || e.Type != core.EventModify || e.Origin != core.OriginLocal {
+			t.Fatalf("unexpected event: %+v", e)
+		}
+		if e.Document == nil || e.Document.Content != "v2" {
+			t.Errorf("expected new document, got %+v", e.Document)
+		}
+		if e.Previous == nil || e.Previous.Metadata["status"] != "draft" {
+			t.Errorf("expected previous version, got %+v", e.Previous)
+		}
+		if len(e.Diff) != 1 || e.Diff[0] != (core.FieldChange{Field: "status", Old: "draft", New: "published"}) {
+			t.Errorf("unexpected diff: %+v", e.Diff)
+		}
+		head, err := repo.git.Run("rev-parse", "HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Commit == "" || e.Commit != head {
+			t.Errorf("expected commit %q, got %q", head, e.Commit)
+		}
+	})
+
+	t.Run("External Write", func(t *testing.T) {
+		content := "---\nstatus: archived\ntag: a\n---\nv3"
+		if err := os.WriteFile(filepath.Join(tmpDir, "note.md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		e := next()
+		if e.ID != "note" || e.Origin != core.OriginExternal {
+			t.Fatalf("unexpected event: %+v", e)
+		}
+		if e.Document == nil || e.Document.Metadata["status"] != "archived" {
+			t.Errorf("expected new document, got %+v", e.Document)
+		}
+		if e.Previous == nil || e.Previous.Metadata["status"] != "published" {
+			t.Errorf("expected previous version, got %+v", e.Previous)
+		}
+		if e.Commit != "" {
+			t.Errorf("uncommitted change should have no commit, got %q", e.Commit)
+		}
+	})
+
+	t.Run("Local Delete", func(t *testing.T) {
+		if err := repo.Delete(ctx, "note"); err != nil {
+			t.Fatalf("failed to delete: %v", err)
+		}
+
+		e := next()
+		if e.ID != "note" || e.Type != core.EventDelete || e.Origin != core.OriginLocal {
+			t.Fatalf("unexpected event: %+v", e)
+		}
+		if e.Document != nil || e.Previous == nil {
+			t.Errorf("expected only the previous version, got %+v / %+v", e.Document, e.Previous)
+		}
+
+		select {
+		case e := <-events:
+			t.Errorf("unexpected extra event: %+v", e)
+		case <-time.After(300 * time.Millisecond):
+		}
+	})
+}
+
+func TestFailedDeleteKeepsIndex(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	tmpDir := t.TempDir()
+	repo := NewRepository(Config{
+		Path:      tmpDir,
+		AutoInit:  true,
+		SystemDir: ".loam",
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ChangeLog: true,
+	})
+	ctx := context.Background()
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	if err := repo.Save(ctx, core.Document{ID: "note", Content: "v1"}); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	hook := filepath.Join(tmpDir, ".git", "hooks", "pre-commit")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, "note"); err == nil {
+		t.Fatal("expected the rejected commit to fail the delete")
+	}
+
+	if repo.cache.Peek("note.md") == nil {
+		t.Error("expected the index to keep the document of a failed delete")
+	}
+	changes, err := repo.Changes(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range changes {
+		if c.Type == core.EventDelete {
+			t.Errorf("failed delete was reported: %+v", c)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package fs
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// isCollectionFile reports whether relPath is a collection whose rows are documents.
+// Only CSV is implemented (see flattenCollection).
+func isCollectionFile(relPath string) bool {
+	return filepath.Ext(relPath) == ".csv"
+}
+
+// collectionOf returns the collection file of a row path (e.g. "users.csv" for
+// "users.csv/jane"), or relPath itself if it is not a row.
+func collectionOf(relPath string) string {
+	if i := strings.Index(relPath, ".csv/"); i >= 0 {
+		return relPath[:i+len(".csv")]
+	}
+	return relPath
+}
+
+// rowID returns the canonical ID of a collection row, e.g. "users.csv/jane".
+func (r *Repository) rowID(collectionPath, key string) string {
+	relPath, err := filepath.Rel(r.Path, collectionPath)
+	if err != nil {
+		return key
+	}
+	return filepath.ToSlash(relPath) + "/" + key
+}
+
+// rowIndex maps the rows of a collection by ID.
+func rowIndex(docs []core.Document) map[string]core.Document {
+	rows := make(map[string]core.Document, len(docs))
+	for _, doc := range docs {
+		rows[doc.ID] = doc
+	}
+	return rows
+}
+
+// snapshotRows records the current rows of a collection file, so later changes can be diffed.
+func (s *directoryWatchSource) snapshotRows(absPath, relPath string) {
+	if docs, err := s.repo.flattenCollection(absPath, relPath); err == nil {
+		s.rows[relPath] = rowIndex(docs)
+	}
+}
+
+// emitRowChanges diffs a collection file against its previous snapshot and, if emit
+// is set, reports one event per created, modified or deleted row matching the pattern.
+// The snapshot is updated either way, so our own writes are not reported later.
+func (s *directoryWatchSource) emitRowChanges(ctx context.Context, event fsnotify.Event, relPath string, emit bool) {
+	prev := s.rows[relPath]
+	var current map[string]core.Document
+	if !event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+		docs, err := s.repo.flattenCollection(event.Name, relPath)
+		if err != nil {
+			// Unreadable or partially written: keep the snapshot until the next event.
+			return
+		}
+		current = rowIndex(docs)
+	}
+	if current == nil {
+		delete(s.rows, relPath)
+	} else {
+		s.rows[relPath] = current
+	}
+	if !emit {
+		return
+	}
+
+	for _, e := range diffRows(prev, current) {
+		if !s.repo.matchPattern(s.pattern, e.ID) {
+			continue
+		}
+		e.Path = e.ID
+		if s.repo.config.RichEvents {
+			var before *indexEntry
+			if old, ok := prev[e.ID]; ok {
+				before = &indexEntry{ID: old.ID, Metadata: old.Metadata}
+			}
+			var doc *core.Document
+			if row, ok := current[e.ID]; ok {
+				doc = &row
+			}
+			s.repo.enrichEvent(&e, e.ID, before, doc, core.OriginExternal)
+		} else {
+			e.Origin = core.OriginExternal
+		}
+		s.Emit(ctx, e)
+	}
+}
+
+// diffRows returns the row events turning prev into current, in ID order.
+func diffRows(prev, current map[string]core.Document) []core.Event {
+	now := time.Now().Unix()
+	var evts []core.Event
+	for _, id := range sortedKeys(current) {
+		row := current[id]
+		old, ok := prev[id]
+		switch {
+		case !ok:
+			evts = append(evts, core.Event{Type: core.EventCreate, ID: id, Timestamp: now})
+		case old.Content != row.Content || !reflect.DeepEqual(old.Metadata, row.Metadata):
+			evts = append(evts, core.Event{Type: core.EventModify, ID: id, Timestamp: now})
+		}
+	}
+	for _, id := range sortedKeys(prev) {
+		if _, ok := current[id]; !ok {
+			evts = append(evts, core.Event{Type: core.EventDelete, ID: id, Timestamp: now})
+		}
+	}
+	return evts
+}
+
+func sortedKeys(rows map[string]core.Document) []string {
+	keys := make([]string, 0, len(rows))
+	for id := range rows {
+		keys = append(keys, id)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,100 @@
+package fs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+func TestWatchCollectionRows(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := NewRepository(Config{
+		Path:       tmpDir,
+		Gitless:    true,
+		SystemDir:  ".loam",
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		RichEvents: true,
+	})
+	ctx := context.Background()
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	csvPath := filepath.Join(tmpDir, "users.csv")
+	if err := os.WriteFile(csvPath, []byte("id,name,role\njane,Jane,admin\njohn,John,user\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer func() {
+		cancel()
+		time.Sleep(100 * time.Millisecond)
+	}()
+	events, err := repo.Watch(watchCtx, "users.csv/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	collect := func(n int) map[string]core.Event {
+		t.Helper()
+		got := make(map[string]core.Event)
+		for len(got) < n {
+			select {
+			case e := <-events:
+				got[e.ID] = e
+			case <-watchCtx.Done():
+				t.Fatalf("timed out, got %+v", got)
+			}
+		}
+		return got
+	}
+
+	t.Run("External Rewrite", func(t *testing.T) {
+		// jane changes role, john is removed, mary is added.
+		if err := os.WriteFile(csvPath, []byte("id,name,role\njane,Jane,owner\nmary,Mary,user\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		got := collect(3)
+		want := map[string]core.EventType{
+			"users.csv/jane": core.EventModify,
+			"users.csv/john": core.EventDelete,
+			"users.csv/mary": core.EventCreate,
+		}
+		for id, typ := range want {
+			if e := got[id]; e.Type != typ || e.Origin != core.OriginExternal {
+				t.Errorf("%s: expected external %s, got %+v", id, typ, e)
+			}
+		}
+		jane := got["users.csv/jane"]
+		if len(jane.Diff) != 1 || jane.Diff[0] != (core.FieldChange{Field: "role", Old: "admin", New: "owner"}) {
+			t.Errorf("unexpected diff: %+v", jane.Diff)
+		}
+	})
+
+	t.Run("Local Row Save", func(t *testing.T) {
+		if err := repo.Save(ctx, core.Document{ID: "users/mary", Metadata: core.Metadata{"name": "Mary", "role": "admin"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		e := collect(1)["users.csv/mary"]
+		if e.Type != core.EventModify || e.Origin != core.OriginLocal {
+			t.Errorf("unexpected event: %+v", e)
+		}
+		if e.Previous == nil || e.Previous.Metadata["role"] != "user" {
+			t.Errorf("expected previous row, got %+v", e.Previous)
+		}
+
+		select {
+		case e := <-events:
+			t.Errorf("unexpected echo: %+v", e)
+		case <-time.After(300 * time.Millisecond):
+		}
+	})
+}
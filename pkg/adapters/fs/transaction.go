@@ -2,22 +2,42 @@ package fs
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/aretw0/loam/pkg/core"
 )
 
+// savepoint is a snapshot of the staged state of a transaction.
+type savepoint struct {
+	name    string
+	staged  map[string]core.Document
+	deleted map[string]bool
+}
+
 // Transaction implements core.Transaction for the filesystem.
 type Transaction struct {
 	repo    *Repository
 	staged  map[string]core.Document // ID -> Document
 	deleted map[string]bool          // ID -> bool
+	reads   map[string]string        // ID -> version seen by Get ("" if absent)
+	rows    map[string]*indexEntry   // Row ID -> row before the commit (nil if new), see publishChanges
+	marks   []savepoint
 	mu      sync.Mutex
 	closed  bool
+
+	// Two-phase commit: the journal recorded by Prepare and the lock it holds.
+	prepared *journal
+	unlock   func()
 }
 
 // NewTransaction creates a new transaction.
@@ -26,6 +46,7 @@ func NewTransaction(repo *Repository) *Transaction {
 		repo:    repo,
 		staged:  make(map[string]core.Document),
 		deleted: make(map[string]bool),
+		reads:   make(map[string]string),
 	}
 }
 
@@ -44,6 +65,7 @@ func (t *Transaction) Save(ctx context.Context, doc core.Document) error {
 }
 
 // Get retrieves a document, favoring staged changes.
+// Documents read from the repository are recorded and validated at Commit.
 func (t *Transaction) Get(ctx context.Context, id string) (core.Document, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -61,7 +83,14 @@ func (t *Transaction) Get(ctx context.Context, id string) (core.Document, error)
 	}
 
 	// Fallback to repo
-	return t.repo.Get(ctx, id)
+	doc, version, err := t.repo.readVersion(ctx, id)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return doc, err
+	}
+	if _, seen := t.reads[id]; !seen {
+		t.reads[id] = version
+	}
+	return doc, err
 }
 
 // Delete stages a document for deletion.
@@ -78,7 +107,97 @@ func (t *Transaction) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// Savepoint snapshots the staged saves and deletes under name.
+func (t *Transaction) Savepoint(ctx context.Context, name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return fmt.Errorf("transaction closed")
+	}
+
+	t.marks = append(t.marks, savepoint{
+		name:    name,
+		staged:  maps.Clone(t.staged),
+		deleted: maps.Clone(t.deleted),
+	})
+	return nil
+}
+
+// RollbackTo restores the staged state of the most recent savepoint named name,
+// discarding any savepoints created after it.
+// Documents read since the savepoint stay in the read set.
+func (t *Transaction) RollbackTo(ctx context.Context, name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return fmt.Errorf("transaction closed")
+	}
+
+	for i := len(t.marks) - 1; i >= 0; i-- {
+		if t.marks[i].name == name {
+			t.staged = maps.Clone(t.marks[i].staged)
+			t.deleted = maps.Clone(t.marks[i].deleted)
+			t.marks = t.marks[:i+1]
+			return nil
+		}
+	}
+	return fmt.Errorf("savepoint %q not found", name)
+}
+
+// List returns the repository listing with staged saves and deletes applied on top.
+func (t *Transaction) List(ctx context.Context) ([]core.Document, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, fmt.Errorf("transaction closed")
+	}
+
+	docs, err := t.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]core.Document, 0, len(docs)+len(t.staged))
+	seen := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		if t.deleted[doc.ID] {
+			continue
+		}
+		if staged, ok := t.staged[doc.ID]; ok {
+			doc = staged
+		}
+		seen[doc.ID] = true
+		result = append(result, doc)
+	}
+
+	// New documents, in a stable order.
+	ids := make([]string, 0, len(t.staged))
+	for id := range t.staged {
+		if !seen[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		result = append(result, t.staged[id])
+	}
+	return result, nil
+}
+
+// Query returns the documents matching q, including staged changes.
+func (t *Transaction) Query(ctx context.Context, q core.Query) ([]core.Document, error) {
+	docs, err := t.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return q.Apply(docs), nil
+}
+
 // Commit applies all staged changes.
+// For a prepared transaction, it applies the journal recorded by Prepare.
 func (t *Transaction) Commit(ctx context.Context, changeReason string) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -86,19 +205,103 @@ func (t *Transaction) Commit(ctx context.Context, changeReason string) error {
 	if t.closed {
 		return fmt.Errorf("transaction already closed")
 	}
+	if t.prepared != nil {
+		return t.commitPrepared(ctx)
+	}
 
-	// 1. Git Lock (if applicable)
-	if !t.repo.config.Gitless {
-		unlock, err := t.repo.git.Lock()
-		if err != nil {
-			return fmt.Errorf("failed to acquire git lock: %w", err)
-		}
-		defer unlock()
+	// 1. Lock. Also taken in gitless mode: recovery relies on it to tell
+	// live transactions from interrupted ones.
+	unlock, err := t.repo.git.LockContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire git lock: %w", err)
+	}
+	defer unlock()
+
+	// 2. Validate and render the journal
+	j, err := t.prepareLocked(ctx, changeReason)
+	if err != nil {
+		return err
+	}
+
+	// 3. Write-ahead journal: once recorded, the transaction survives a crash
+	// (rolled forward on the next Initialize).
+	if err := t.repo.writeJournal(j); err != nil {
+		return err
+	}
+
+	// 4. Apply writes to disk and commit; any failure rolls everything back.
+	if err := t.apply(j); err != nil {
+		return t.repo.abortJournal(j, err)
+	}
+	return t.finish(ctx, j)
+}
+
+// Prepare implements the first phase of a two-phase commit: it locks the vault,
+// validates the transaction and records its journal without applying it.
+// The lock is held until Commit or Rollback.
+func (t *Transaction) Prepare(ctx context.Context, changeReason string, coord core.Coordination) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return fmt.Errorf("transaction already closed")
+	}
+	if t.prepared != nil {
+		return fmt.Errorf("transaction already prepared")
+	}
+
+	unlock, err := t.repo.git.LockContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire git lock: %w", err)
+	}
+
+	j, err := t.prepareLocked(ctx, changeReason)
+	if err != nil {
+		unlock()
+		return err
+	}
+	j.State = journalPrepared
+	j.Coordinator = coord.ID
+	j.Decision = coord.Decision
+	if err := t.repo.writeJournal(j); err != nil {
+		unlock()
+		return err
+	}
+
+	t.prepared = j
+	t.unlock = unlock
+	return nil
+}
+
+// commitPrepared applies a prepared journal. The commit decision is already
+// taken, so failures leave the journal for recovery instead of rolling back.
+func (t *Transaction) commitPrepared(ctx context.Context) error {
+	j := t.prepared
+	defer func() {
+		t.unlock()
+		t.prepared = nil
+		t.closed = true
+	}()
+
+	j.State = journalCommitting
+	if err := t.repo.writeJournal(j); err != nil {
+		return fmt.Errorf("%w (transaction will be completed on next Initialize)", err)
+	}
+	if err := t.apply(j); err != nil {
+		return fmt.Errorf("%w (transaction will be completed on next Initialize)", err)
+	}
+	return t.finish(ctx, j)
+}
+
+// prepareLocked validates the read set and renders every staged change to its
+// final content. Caller must hold the lock.
+func (t *Transaction) prepareLocked(ctx context.Context, changeReason string) (*journal, error) {
+	// Validate the read set: fail if another writer changed what we read.
+	if err := t.validateReads(ctx); err != nil {
+		return nil, err
 	}
 
-	// 2. Apply writes to disk
-	var filesToAdd []string
-	var filesToRm []string
+	var ops []journalOp
 
 	// Grouping Phase
 	collectionBatches := make(map[string]map[string]core.Document) // collectionPath -> map[key]Doc
@@ -112,48 +315,37 @@ func (t *Transaction) Commit(ctx context.Context, changeReason string) error {
 				collectionExts[collectionPath] = colExt
 			}
 			collectionBatches[collectionPath][key] = n
-			// Git add the collection file eventually?
-			// We handle collection Git add below.
+			if t.repo.reportsLocal() {
+				if t.rows == nil {
+					t.rows = make(map[string]*indexEntry)
+				}
+				rowID := t.repo.rowID(collectionPath, key)
+				t.rows[rowID] = nil
+				if old, err := t.repo.getFromCollection(id); err == nil {
+					t.rows[rowID] = &indexEntry{ID: rowID, Metadata: old.Metadata}
+				}
+			}
 		} else {
 			fileWrites[id] = n
 		}
 	}
 
-	// Execution Phase (Collections)
+	// Collections
 	for colPath, batch := range collectionBatches {
-		ext := collectionExts[colPath]
-		if err := t.repo.saveBatchToCollection(colPath, ext, batch); err != nil {
-			return fmt.Errorf("failed to save batch for collection %s: %w", colPath, err)
+		data, err := t.repo.renderCollectionBatch(colPath, collectionExts[colPath], batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save batch for collection %s: %w", colPath, err)
 		}
-
 		relPath, err := filepath.Rel(t.repo.Path, colPath)
-		if err == nil {
-			filesToAdd = append(filesToAdd, relPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve collection %s: %w", colPath, err)
 		}
-
-		// Cache Invalidation for collection?
-		// Currently Cache maps file -> indexEntry.
-		// If we modify Collection, we should update cache for the Collection File.
-		// Ideally we update access time.
-		t.repo.cache.Set(filepath.Base(colPath), &indexEntry{
-			ID:           filepath.ToSlash(relPath), // ID of the collection file
-			LastModified: time.Now(),
-		})
+		ops = append(ops, journalOp{Path: filepath.ToSlash(relPath), Data: data})
 	}
 
-	// Execution Phase (Files)
+	// Files
 	for id, n := range fileWrites {
-		// Simplification: Always use .md for now
-		filename := id + ".md"
-		if len(filepath.Ext(id)) > 0 {
-			filename = id
-		}
-		fullPath := filepath.Join(t.repo.Path, filename)
-		filesToAdd = append(filesToAdd, filename)
-
-		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
-			return fmt.Errorf("failed to create directories for %s: %w", id, err)
-		}
+		filename := txFilename(id)
 
 		ext := filepath.Ext(filename)
 		serializer, ok := t.repo.serializers[ext]
@@ -164,69 +356,224 @@ func (t *Transaction) Commit(ctx context.Context, changeReason string) error {
 		// Use shared serialization logic
 		buf, err := serializer.Serialize(n, t.repo.config.MetadataKey)
 		if err != nil {
-			return fmt.Errorf("failed to serialize %s: %w", id, err)
+			return nil, fmt.Errorf("failed to serialize %s: %w", id, err)
 		}
+		ops = append(ops, journalOp{Path: filepath.ToSlash(filename), Data: buf})
+	}
 
-		if err := writeFileAtomic(fullPath, buf, 0644); err != nil {
-			return fmt.Errorf("failed to write file %s: %w", id, err)
+	// Deletes
+	for id := range t.deleted {
+		ops = append(ops, journalOp{Path: filepath.ToSlash(id + ".md"), Delete: true})
+	}
+
+	msg := changeReason
+	if msg == "" {
+		msg = "batch transaction update"
+	}
+
+	j, err := t.repo.newJournal(ops, msg, commitOptions(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare transaction journal: %w", err)
+	}
+	return j, nil
+}
+
+// apply writes the journal to disk and commits it to git.
+func (t *Transaction) apply(j *journal) error {
+	if t.repo.reportsLocal() {
+		// Reported as local changes by finish instead of by the watcher.
+		for _, op := range j.Ops {
+			var data []byte
+			if !op.Delete {
+				data = op.Data
+			}
+			t.repo.ignoreWrite(filepath.Join(t.repo.Path, filepath.FromSlash(op.Path)), data)
 		}
+	}
+	if err := t.repo.applyJournal(j); err != nil {
+		return err
+	}
+	if !t.repo.config.Gitless {
+		return t.repo.commitJournal(j)
+	}
+	return nil
+}
 
-		// Update Cache
-		// Because we're inside a transaction, we might not have the full path easily accessible for relPath calculation without context of repo path.
-		// But fullPath is constructed above.
-		relPath, _ := filepath.Rel(t.repo.Path, fullPath)
-		relPath = filepath.ToSlash(relPath)
+// finish removes the journal of an applied transaction, updates the cache and closes it.
+// It also reports one local change per changed file (see publishLocal). The changes
+// are committed even if it returns an error: the journal could not be dropped.
+func (t *Transaction) finish(ctx context.Context, j *journal) error {
+	journalErr := t.repo.removeJournal(j)
 
-		t.repo.cache.Set(relPath, &indexEntry{
+	var prevs []*indexEntry
+	if t.repo.reportsLocal() {
+		for _, op := range j.Ops {
+			prevs = append(prevs, t.repo.cache.Peek(op.Path))
+		}
+	}
+
+	for id, n := range t.staged {
+		if colPath, _, _, found := t.repo.findCollection(id); found {
+			relPath, _ := filepath.Rel(t.repo.Path, colPath)
+			t.repo.cache.Set(filepath.Base(colPath), &indexEntry{
+				ID:           filepath.ToSlash(relPath), // ID of the collection file
+				LastModified: time.Now(),
+			})
+			continue
+		}
+		// The real mtime lets reconciliation and the watcher tell this write is indexed.
+		mtime := time.Now()
+		if info, err := os.Stat(filepath.Join(t.repo.Path, txFilename(id))); err == nil {
+			mtime = info.ModTime()
+		}
+		t.repo.cache.Set(filepath.ToSlash(txFilename(id)), &indexEntry{
 			ID:           id,
 			Metadata:     n.Metadata,
-			LastModified: time.Now(),
+			LastModified: mtime,
 		})
 	}
 
-	// Process Deletes
 	for id := range t.deleted {
-		filename := id + ".md"
-		fullPath := filepath.Join(t.repo.Path, filename)
-		filesToRm = append(filesToRm, filename)
+		t.repo.cache.Delete(filepath.ToSlash(id + ".md"))
+	}
 
-		if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("failed to remove file %s: %w", id, err)
-		}
+	// Flush Cache to disk
+	if err := t.repo.cache.Save(); err != nil {
+		// Log error?
 	}
 
-	// 3. Git Commit
-	if !t.repo.config.Gitless {
-		if len(filesToAdd) > 0 {
-			if err := t.repo.git.Add(filesToAdd...); err != nil {
-				return fmt.Errorf("failed to git add: %w", err)
-			}
+	if t.repo.reportsLocal() {
+		t.publishChanges(ctx, j, prevs)
+	}
+
+	t.closed = true
+	if journalErr != nil {
+		return fmt.Errorf("transaction committed, but %w", journalErr)
+	}
+	return nil
+}
+
+// publishChanges reports a local change for each operation of an applied journal,
+// or for each staged row of a collection file.
+// prevs holds the index entry of each operation before the transaction.
+func (t *Transaction) publishChanges(ctx context.Context, j *journal, prevs []*indexEntry) {
+	byPath := make(map[string]core.Document, len(t.staged))
+	for id, n := range t.staged {
+		byPath[filepath.ToSlash(txFilename(id))] = n
+	}
+
+	// Staged rows, reported one by one instead of their collection file.
+	rowsByPath := make(map[string][]core.Document)
+	for id, n := range t.staged {
+		if colPath, _, key, found := t.repo.findCollection(id); found {
+			row := n
+			row.ID = t.repo.rowID(colPath, key)
+			path := collectionOf(row.ID)
+			rowsByPath[path] = append(rowsByPath[path], row)
 		}
+	}
 
-		if len(filesToRm) > 0 {
-			if err := t.repo.git.Rm(filesToRm...); err != nil {
-				return fmt.Errorf("failed to git rm: %w", err)
+	now := time.Now().Unix()
+	for i, op := range j.Ops {
+		if rows, ok := rowsByPath[op.Path]; ok {
+			sort.Slice(rows, func(a, b int) bool { return rows[a].ID < rows[b].ID })
+			for _, row := range rows {
+				e := core.Event{Type: core.EventModify, ID: row.ID, Timestamp: now}
+				prev := t.rows[row.ID]
+				if prev == nil {
+					e.Type = core.EventCreate
+				}
+				t.repo.publishLocal(ctx, e, row.ID, prev, &row)
 			}
+			continue
 		}
-
-		msg := changeReason
-		if msg == "" {
-			msg = "batch transaction update"
+		id, err := t.repo.resolveID(filepath.Join(t.repo.Path, filepath.FromSlash(op.Path)))
+		if err != nil {
+			continue
 		}
-		if err := t.repo.git.Commit(msg); err != nil {
-			return fmt.Errorf("failed to git commit: %w", err)
+		e := core.Event{Type: core.EventModify, ID: id, Timestamp: now}
+		var doc *core.Document
+		switch {
+		case op.Delete:
+			e.Type = core.EventDelete
+		case !op.Existed:
+			e.Type = core.EventCreate
 		}
+		if !op.Delete {
+			if n, ok := byPath[op.Path]; ok {
+				doc = &n
+			} else if d, err := t.repo.Get(ctx, id); err == nil {
+				doc = &d // Collection file
+			}
+		}
+		t.repo.publishLocal(ctx, e, op.Path, prevs[i], doc)
 	}
+}
 
-	// Flush Cache to disk
-	if err := t.repo.cache.Save(); err != nil {
-		// Log error?
+// txFilename returns the file a staged document is written to.
+// Simplification: Always use .md for now
+func txFilename(id string) string {
+	if len(filepath.Ext(id)) > 0 {
+		return id
 	}
+	return id + ".md"
+}
 
-	t.closed = true
+// DecisionLocation implements core.DecisionLog.
+func (t *Transaction) DecisionLocation(id string) string {
+	return filepath.Join(t.repo.Path, t.repo.config.SystemDir, decisionDirName, id)
+}
+
+// RecordDecision implements core.DecisionLog by durably writing the decision file.
+func (t *Transaction) RecordDecision(ctx context.Context, id string) error {
+	path := t.DecisionLocation(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte("commit\n"), 0644)
+}
+
+// ClearDecision implements core.DecisionLog.
+func (t *Transaction) ClearDecision(ctx context.Context, id string) error {
+	if err := os.Remove(t.DecisionLocation(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	return nil
 }
 
+// validateReads checks that every document read by the transaction is unchanged.
+// Caller must hold the lock.
+func (t *Transaction) validateReads(ctx context.Context) error {
+	for id, seen := range t.reads {
+		_, current, err := t.repo.readVersion(ctx, id)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to validate read of %s: %w", id, err)
+		}
+		if current != seen {
+			return fmt.Errorf("%w: %s changed since it was read", core.ErrConflict, id)
+		}
+	}
+	return nil
+}
+
+// readVersion reads a document along with its version: a hash of its content
+// and metadata. Missing documents have the empty version.
+func (r *Repository) readVersion(ctx context.Context, id string) (core.Document, string, error) {
+	doc, err := r.Get(ctx, id)
+	if err != nil {
+		return doc, "", err
+	}
+	data, err := json.Marshal(struct {
+		Content  string
+		Metadata core.Metadata
+	}{doc.Content, doc.Metadata})
+	if err != nil {
+		return doc, "", err
+	}
+	sum := sha256.Sum256(data)
+	return doc, hex.EncodeToString(sum[:]), nil
+}
+
 // Rollback discards all staged changes.
 func (t *Transaction) Rollback(ctx context.Context) error {
 	t.mu.Lock()
@@ -236,9 +583,17 @@ func (t *Transaction) Rollback(ctx context.Context) error {
 		return nil
 	}
 
+	// A prepared transaction has not touched any document: drop its journal.
+	var err error
+	if t.prepared != nil {
+		err = t.repo.removeJournal(t.prepared)
+		t.unlock()
+		t.prepared = nil
+	}
+
 	// Just clear memory
 	t.staged = nil
 	t.deleted = nil
 	t.closed = true
-	return nil
+	return err
 }
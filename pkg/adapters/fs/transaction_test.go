@@ -2,6 +2,7 @@ package fs
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log/slog"
 	"os"
@@ -175,3 +176,237 @@ func TestTransaction(t *testing.T) {
 		}
 	})
 }
+
+func TestTransactionConflict(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := NewRepository(Config{
+		Path:     tmpDir,
+		AutoInit: true,
+		Gitless:  true,
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	ctx := context.Background()
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	if err := repo.Save(ctx, core.Document{ID: "counter", Content: "0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Detects Concurrent Write", func(t *testing.T) {
+		tx, _ := repo.Begin(ctx)
+		doc, err := tx.Get(ctx, "counter")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Another writer changes the document after it was read.
+		if err := repo.Save(ctx, core.Document{ID: "counter", Content: "changed"}); err != nil {
+			t.Fatal(err)
+		}
+
+		doc.Content = doc.Content + "+1"
+		tx.Save(ctx, doc)
+		if err := tx.Commit(ctx, "increment"); !errors.Is(err, core.ErrConflict) {
+			t.Fatalf("expected ErrConflict, got %v", err)
+		}
+	})
+
+	t.Run("Detects Phantom Create", func(t *testing.T) {
+		tx, _ := repo.Begin(ctx)
+		if _, err := tx.Get(ctx, "unclaimed"); !os.IsNotExist(err) {
+			t.Fatalf("expected not exist, got %v", err)
+		}
+		repo.Save(ctx, core.Document{ID: "unclaimed", Content: "taken"})
+
+		tx.Save(ctx, core.Document{ID: "unclaimed", Content: "mine"})
+		if err := tx.Commit(ctx, "claim"); !errors.Is(err, core.ErrConflict) {
+			t.Fatalf("expected ErrConflict, got %v", err)
+		}
+	})
+
+	t.Run("Retrying Transaction", func(t *testing.T) {
+		svc := core.NewService(repo)
+		attempts := 0
+		err := svc.WithRetryingTransaction(ctx, 3, func(tx core.Transaction) error {
+			attempts++
+			doc, err := tx.Get(ctx, "counter")
+			if err != nil {
+				return err
+			}
+			if attempts == 1 {
+				// Interfere on the first attempt only.
+				repo.Save(ctx, core.Document{ID: "counter", Content: "interfered"})
+			}
+			doc.Content = doc.Content + "+1"
+			return tx.Save(ctx, doc)
+		})
+		if err != nil {
+			t.Fatalf("expected retry to succeed, got %v", err)
+		}
+		if attempts != 2 {
+			t.Errorf("expected 2 attempts, got %d", attempts)
+		}
+		doc, _ := repo.Get(ctx, "counter")
+		if doc.Content != "interfered+1" {
+			t.Errorf("expected retried update on top of concurrent write, got %q", doc.Content)
+		}
+	})
+}
+
+func TestTransactionListAndQuery(t *testing.T) {
+	repo, _ := setupCSVRepo(t, "", "id,name,role\njane,Jane Doe,admin\nbob,Bob Smith,user\n")
+	ctx := context.Background()
+	if err := repo.Save(ctx, core.Document{ID: "invoices/1", Content: "a", Metadata: core.Metadata{"total": 10}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Save(ctx, core.Document{ID: "invoices/2", Content: "b", Metadata: core.Metadata{"total": 20}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, _ := repo.Begin(ctx)
+	defer tx.Rollback(ctx)
+	tx.Save(ctx, core.Document{ID: "invoices/3", Content: "c", Metadata: core.Metadata{"total": 30}})
+	tx.Delete(ctx, "invoices/1")
+	tx.Save(ctx, core.Document{ID: "users.csv/bob", Metadata: core.Metadata{"name": "Bob Smith", "role": "admin"}})
+
+	docs, err := tx.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	ids := make(map[string]bool)
+	for _, d := range docs {
+		ids[d.ID] = true
+	}
+	for _, want := range []string{"invoices/2", "invoices/3", "users.csv/jane", "users.csv/bob"} {
+		if !ids[want] {
+			t.Errorf("expected %s in transaction listing", want)
+		}
+	}
+	if ids["invoices/1"] {
+		t.Error("expected staged delete to hide invoices/1")
+	}
+
+	invoices, err := tx.Query(ctx, core.Query{
+		Pattern: "invoices/*",
+		Where:   []core.Condition{{Field: "total", Op: core.OpGte, Value: 20}},
+		OrderBy: "-total",
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(invoices) != 2 || invoices[0].ID != "invoices/3" || invoices[1].ID != "invoices/2" {
+		t.Errorf("unexpected query result: %+v", invoices)
+	}
+
+	admins, _ := tx.Query(ctx, core.Query{Pattern: "users.csv/*", Where: []core.Condition{{Field: "role", Op: core.OpEq, Value: "admin"}}})
+	if len(admins) != 2 {
+		t.Errorf("expected staged collection row to be visible to queries, got %+v", admins)
+	}
+}
+
+func TestTransactionSavepoints(t *testing.T) {
+	repo := NewRepository(Config{Path: t.TempDir(), AutoInit: true, Gitless: true, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	ctx := context.Background()
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	repo.Save(ctx, core.Document{ID: "existing", Content: "keep me"})
+
+	tx, _ := repo.Begin(ctx)
+	tx.Save(ctx, core.Document{ID: "a", Content: "a"})
+	if err := tx.Savepoint(ctx, "sp1"); err != nil {
+		t.Fatal(err)
+	}
+	tx.Save(ctx, core.Document{ID: "a", Content: "a2"})
+	tx.Save(ctx, core.Document{ID: "b", Content: "b"})
+	tx.Delete(ctx, "existing")
+	tx.Savepoint(ctx, "sp2")
+	tx.Save(ctx, core.Document{ID: "c", Content: "c"})
+
+	if err := tx.RollbackTo(ctx, "sp1"); err != nil {
+		t.Fatalf("RollbackTo failed: %v", err)
+	}
+	if err := tx.RollbackTo(ctx, "sp2"); err == nil {
+		t.Error("expected savepoints after sp1 to be discarded")
+	}
+
+	if doc, _ := tx.Get(ctx, "a"); doc.Content != "a" {
+		t.Errorf("expected a to be restored to its savepoint state, got %q", doc.Content)
+	}
+	if _, err := tx.Get(ctx, "b"); !os.IsNotExist(err) {
+		t.Errorf("expected b to be discarded, got %v", err)
+	}
+	if _, err := tx.Get(ctx, "existing"); err != nil {
+		t.Errorf("expected delete of existing to be undone, got %v", err)
+	}
+
+	// Staging after a rollback must not leak into the savepoint.
+	tx.Save(ctx, core.Document{ID: "d", Content: "d"})
+	tx.RollbackTo(ctx, "sp1")
+	if _, err := tx.Get(ctx, "d"); !os.IsNotExist(err) {
+		t.Errorf("expected d to be discarded by second rollback, got %v", err)
+	}
+
+	if err := tx.Commit(ctx, "savepoints"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get(ctx, "b"); !os.IsNotExist(err) {
+		t.Error("expected rolled back document not to be committed")
+	}
+}
+
+func TestNestedTransactionAcrossServices(t *testing.T) {
+	ctx := context.Background()
+	open := func(t *testing.T) *Repository {
+		t.Helper()
+		repo := NewRepository(Config{Path: t.TempDir(), AutoInit: true, Gitless: true, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+		if err := repo.Initialize(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	}
+	repoA, repoB := open(t), open(t)
+	svcA, svcB := core.NewService(repoA), core.NewService(repoB)
+
+	err := svcA.WithTransaction(ctx, func(txA core.Transaction) error {
+		ctxA := svcA.ContextWithTransaction(ctx, txA)
+		if err := txA.Save(ctx, core.Document{ID: "a", Content: "a"}); err != nil {
+			return err
+		}
+		// B does not belong to A's transaction: it must run its own.
+		return svcB.WithTransaction(ctxA, func(txB core.Transaction) error {
+			if txB == txA {
+				t.Error("expected service B not to nest in the transaction of service A")
+			}
+			return txB.Save(ctx, core.Document{ID: "b", Content: "b"})
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repoB.Get(ctx, "b"); err != nil {
+		t.Errorf("expected b to be written to vault B: %v", err)
+	}
+	if _, err := repoA.Get(ctx, "b"); !os.IsNotExist(err) {
+		t.Errorf("expected b not to be written to vault A, got %v", err)
+	}
+	if _, err := repoA.Get(ctx, "a"); err != nil {
+		t.Errorf("expected a to be written to vault A: %v", err)
+	}
+
+	// A second Service over the same repository still nests.
+	other := core.NewService(repoA)
+	err = svcA.WithTransaction(ctx, func(txA core.Transaction) error {
+		return other.WithTransaction(svcA.ContextWithTransaction(ctx, txA), func(tx core.Transaction) error {
+			if tx != txA {
+				t.Error("expected a service over the same repository to nest")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
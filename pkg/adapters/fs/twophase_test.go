@@ -0,0 +1,198 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+func TestMultiTransaction(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	ctx := context.Background()
+	open := func(t *testing.T, path string) *Repository {
+		t.Helper()
+		repo := NewRepository(Config{
+			Path:      path,
+			AutoInit:  true,
+			SystemDir: ".loam",
+			Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		})
+		if err := repo.Initialize(ctx); err != nil {
+			t.Fatalf("failed to init repo: %v", err)
+		}
+		return repo
+	}
+
+	t.Run("Commits Across Vaults", func(t *testing.T) {
+		customers, ledger := open(t, t.TempDir()), open(t, t.TempDir())
+		services := []*core.Service{core.NewService(customers), core.NewService(ledger)}
+
+		err := core.WithMultiTransaction(ctx, services, func(txs []core.Transaction) error {
+			if err := txs[0].Save(ctx, core.Document{ID: "acme", Content: "customer"}); err != nil {
+				return err
+			}
+			return txs[1].Save(ctx, core.Document{ID: "entry-1", Content: "debit acme"})
+		})
+		if err != nil {
+			t.Fatalf("multi transaction failed: %v", err)
+		}
+		if _, err := customers.Get(ctx, "acme"); err != nil {
+			t.Errorf("expected customer to be committed: %v", err)
+		}
+		if _, err := ledger.Get(ctx, "entry-1"); err != nil {
+			t.Errorf("expected ledger entry to be committed: %v", err)
+		}
+		entries, _ := os.ReadDir(customers.journalDir())
+		if len(entries) != 0 {
+			t.Errorf("expected no journals left, got %d", len(entries))
+		}
+	})
+
+	t.Run("Rolls Back All When Prepare Fails", func(t *testing.T) {
+		customers, ledger := open(t, t.TempDir()), open(t, t.TempDir())
+		ledger.Save(ctx, core.Document{ID: "balance", Content: "0"})
+		services := []*core.Service{core.NewService(customers), core.NewService(ledger)}
+
+		err := core.WithMultiTransaction(ctx, services, func(txs []core.Transaction) error {
+			txs[0].Save(ctx, core.Document{ID: "acme", Content: "customer"})
+			if _, err := txs[1].Get(ctx, "balance"); err != nil {
+				return err
+			}
+			// A concurrent writer makes the ledger fail validation at prepare.
+			ledger.Save(ctx, core.Document{ID: "balance", Content: "changed"})
+			return txs[1].Save(ctx, core.Document{ID: "balance", Content: "100"})
+		})
+		if !errors.Is(err, core.ErrConflict) {
+			t.Fatalf("expected conflict, got %v", err)
+		}
+		if _, err := customers.Get(ctx, "acme"); !os.IsNotExist(err) {
+			t.Errorf("expected customer change to be rolled back, got %v", err)
+		}
+		if status, _ := customers.git.LockStatus(); status != nil {
+			t.Error("expected prepared participant to release its lock")
+		}
+	})
+
+	t.Run("Runs After-Commit Hooks Only On Commit", func(t *testing.T) {
+		customers, ledger := open(t, t.TempDir()), open(t, t.TempDir())
+		ledger.Save(ctx, core.Document{ID: "balance", Content: "0"})
+
+		var afterCommit []string
+		var hookErr error
+		notify := func(ctx context.Context, m *core.Mutation, next core.MutationHandler) error {
+			if m.Kind != core.MutationCommit {
+				return next(ctx, m)
+			}
+			hookErr = next(ctx, m)
+			if hookErr != nil {
+				return hookErr
+			}
+			for _, change := range m.Changes {
+				// The side effect must see the committed document.
+				if _, err := customers.Get(ctx, change.Document.ID); err != nil {
+					t.Errorf("after-commit hook ran before the commit: %v", err)
+				}
+				afterCommit = append(afterCommit, change.Document.ID)
+			}
+			return nil
+		}
+		services := []*core.Service{core.NewService(customers, core.WithHook(notify)), core.NewService(ledger)}
+
+		err := core.WithMultiTransaction(ctx, services, func(txs []core.Transaction) error {
+			txs[0].Save(ctx, core.Document{ID: "acme", Content: "customer"})
+			if _, err := txs[1].Get(ctx, "balance"); err != nil {
+				return err
+			}
+			// The second participant fails Prepare after the first one prepared.
+			ledger.Save(ctx, core.Document{ID: "balance", Content: "changed"})
+			return txs[1].Save(ctx, core.Document{ID: "balance", Content: "100"})
+		})
+		if !errors.Is(err, core.ErrConflict) {
+			t.Fatalf("expected conflict, got %v", err)
+		}
+		if len(afterCommit) != 0 {
+			t.Errorf("expected no after-commit side effect, got %v", afterCommit)
+		}
+		if !errors.Is(hookErr, core.ErrAborted) {
+			t.Errorf("expected next to report the abort, got %v", hookErr)
+		}
+
+		err = core.WithMultiTransaction(ctx, services, func(txs []core.Transaction) error {
+			txs[0].Save(ctx, core.Document{ID: "acme", Content: "customer"})
+			return txs[1].Save(ctx, core.Document{ID: "balance", Content: "100"})
+		})
+		if err != nil {
+			t.Fatalf("multi transaction failed: %v", err)
+		}
+		if len(afterCommit) != 1 || afterCommit[0] != "acme" {
+			t.Errorf("expected after-commit side effect for acme, got %v", afterCommit)
+		}
+	})
+
+	// crash prepares both participants and abandons them, as if the process died.
+	crash := func(t *testing.T, customers, ledger *Repository, decide bool) {
+		t.Helper()
+		a, b := NewTransaction(customers), NewTransaction(ledger)
+		a.Save(ctx, core.Document{ID: "acme", Content: "customer"})
+		b.Save(ctx, core.Document{ID: "entry-1", Content: "debit acme"})
+
+		coord := core.Coordination{ID: "gtx"}
+		coord.Decision = a.DecisionLocation(coord.ID)
+		for _, tx := range []*Transaction{a, b} {
+			if err := tx.Prepare(ctx, "cross-vault", coord); err != nil {
+				t.Fatalf("prepare failed: %v", err)
+			}
+		}
+		if decide {
+			if err := a.RecordDecision(ctx, coord.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// The dead process leaves its journals; its locks are broken as stale.
+		a.unlock()
+		b.unlock()
+	}
+
+	t.Run("Recovers Decided Transaction", func(t *testing.T) {
+		customersPath, ledgerPath := t.TempDir(), t.TempDir()
+		crash(t, open(t, customersPath), open(t, ledgerPath), true)
+
+		// The ledger restarts first and must still commit.
+		ledger := open(t, ledgerPath)
+		customers := open(t, customersPath)
+		if _, err := customers.Get(ctx, "acme"); err != nil {
+			t.Errorf("expected customer to be recovered: %v", err)
+		}
+		if _, err := ledger.Get(ctx, "entry-1"); err != nil {
+			t.Errorf("expected ledger entry to be recovered: %v", err)
+		}
+		if msg, _ := ledger.git.Run("log", "-1", "--pretty=%s"); msg != "cross-vault" {
+			t.Errorf("expected recovered commit, got %q", msg)
+		}
+	})
+
+	t.Run("Aborts Undecided Transaction", func(t *testing.T) {
+		customersPath, ledgerPath := t.TempDir(), t.TempDir()
+		crash(t, open(t, customersPath), open(t, ledgerPath), false)
+
+		customers, ledger := open(t, customersPath), open(t, ledgerPath)
+		if _, err := customers.Get(ctx, "acme"); !os.IsNotExist(err) {
+			t.Errorf("expected customer change to be aborted, got %v", err)
+		}
+		if _, err := ledger.Get(ctx, "entry-1"); !os.IsNotExist(err) {
+			t.Errorf("expected ledger change to be aborted, got %v", err)
+		}
+		entries, _ := os.ReadDir(ledger.journalDir())
+		if len(entries) != 0 {
+			t.Errorf("expected in-doubt journal to be dropped, got %d", len(entries))
+		}
+	})
+}
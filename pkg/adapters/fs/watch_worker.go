@@ -2,8 +2,12 @@ package fs
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/aretw0/lifecycle/pkg/events"
@@ -12,6 +16,14 @@ import (
 	"github.com/aretw0/loam/pkg/core"
 )
 
+// DefaultPollInterval is the polling period used when Config.PollInterval is zero.
+const DefaultPollInterval = 2 * time.Second
+
+// ErrWatchLimit is reported through Config.ErrorHandler when the OS refuses more
+// watches (e.g. inotify's fs.inotify.max_user_watches); directories beyond the
+// limit are not watched.
+var ErrWatchLimit = errors.New("watch limit reached (raise fs.inotify.max_user_watches)")
+
 // directoryWatchSource bridges fsnotify to the lifecycle Control Plane.
 type directoryWatchSource struct {
 	events.BaseSource
@@ -19,6 +31,10 @@ type directoryWatchSource struct {
 	pattern   string
 	watcher   *fsnotify.Watcher
 	gitLocked bool
+	dirs      map[string]bool // Watched directories (absolute paths).
+
+	// Row snapshots of collection files, by relative path (see emitRowChanges).
+	rows map[string]map[string]core.Document
 }
 
 func newDirectoryWatchSource(repo *Repository, pattern string) *directoryWatchSource {
@@ -26,27 +42,38 @@ func newDirectoryWatchSource(repo *Repository, pattern string) *directoryWatchSo
 		BaseSource: events.NewBaseSource("loam-fs-watcher", 100),
 		repo:       repo,
 		pattern:    pattern,
+		dirs:       make(map[string]bool),
+		rows:       make(map[string]map[string]core.Document),
 	}
 }
 
 func (s *directoryWatchSource) Start(ctx context.Context) error {
+	s.repo.setWatcherActive(true)
+	defer s.repo.setWatcherActive(false)
+
+	s.repo.watchers.Store(s, struct{}{})
+	defer s.repo.watchers.Delete(s)
+
+	if s.repo.config.Polling {
+		return s.poll(ctx)
+	}
+
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		return err
+		s.reportError(fmt.Errorf("fsnotify unavailable, falling back to polling: %w", err))
+		return s.poll(ctx)
 	}
 	s.watcher = watcher
 	defer watcher.Close()
 
-	if err := s.repo.recursiveAdd(watcher); err != nil {
-		return err
+	if err := s.addTree(ctx, s.repo.Path, false); err != nil {
+		s.reportError(fmt.Errorf("fsnotify setup failed, falling back to polling: %w", err))
+		return s.poll(ctx)
 	}
 
 	// Always watch .git to capture index.lock (for git awareness inhibition)
 	_ = watcher.Add(filepath.Join(s.repo.Path, ".git"))
 
-	s.repo.setWatcherActive(true)
-	defer s.repo.setWatcherActive(false)
-
 	for {
 		select {
 		case <-ctx.Done():
@@ -62,8 +89,10 @@ func (s *directoryWatchSource) Start(ctx context.Context) error {
 				continue
 			}
 
-			// If git is locked, inhibit normal events
-			if s.gitLocked {
+			// If git is locked (or this process is rewriting the tree), inhibit normal events.
+			// Directories are still tracked; their files are caught by the reconcile on unlock.
+			inhibited := s.gitLocked || s.repo.pauseWatch.Load() > 0
+			if s.handleDirectory(ctx, event, !inhibited) || inhibited {
 				continue
 			}
 
@@ -84,7 +113,51 @@ func (s *directoryWatchSource) Start(ctx context.Context) error {
 	}
 }
 
+// poll detects changes by periodically reconciling the vault against the index.
+// Changes are delivered to every watcher, as any poller may be the first to see them.
+func (s *directoryWatchSource) poll(ctx context.Context) error {
+	interval := s.repo.config.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	// Baseline: changes made before the watch started are not reported (as with fsnotify).
+	if _, _, err := s.repo.reconcile(ctx, core.OriginExternal, true); err != nil {
+		s.reportError(fmt.Errorf("reconcile error: %w", err))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		// Skip while git or this process rewrites the tree; the next tick catches up.
+		if s.repo.pauseWatch.Load() > 0 {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(s.repo.Path, ".git", "index.lock")); err == nil {
+			continue
+		}
+
+		// The in-memory index is the baseline: it already holds this process's writes.
+		evts, paths, err := s.repo.reconcile(ctx, core.OriginExternal, false)
+		if err != nil {
+			s.reportError(fmt.Errorf("reconcile error: %w", err))
+			continue
+		}
+		s.repo.broadcast(ctx, evts, paths)
+	}
+}
+
 func (s *directoryWatchSource) reconcileAndEmit(ctx context.Context) {
+	if s.repo.pauseWatch.Load() > 0 {
+		// The pausing operation reconciles and broadcasts on its own.
+		return
+	}
 	eventsList, err := s.repo.Reconcile(ctx)
 	if err != nil {
 		if s.repo.config.ErrorHandler != nil {
@@ -93,7 +166,92 @@ func (s *directoryWatchSource) reconcileAndEmit(ctx context.Context) {
 		return
 	}
 	for _, e := range eventsList {
-		s.Emit(ctx, e)
+		if s.repo.matchPattern(s.pattern, e.Path) {
+			s.Emit(ctx, e)
+		}
+	}
+}
+
+// addTree watches root and its subdirectories. With synthesize set, files already
+// present are reported as CREATE events (they may predate the watch).
+func (s *directoryWatchSource) addTree(ctx context.Context, root string, synthesize bool) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if path != root && os.IsNotExist(err) {
+				return nil // Removed while walking.
+			}
+			return err
+		}
+		if !d.IsDir() {
+			if synthesize {
+				s.processFsEvent(ctx, fsnotify.Event{Name: path, Op: fsnotify.Create})
+			} else if rel, err := filepath.Rel(s.repo.Path, path); err == nil && isCollectionFile(rel) {
+				s.snapshotRows(path, filepath.ToSlash(rel))
+			}
+			return nil
+		}
+		// Skip .git and the configured SystemDir (e.g. .loam)
+		if name := d.Name(); name == ".git" || name == s.repo.config.SystemDir {
+			return filepath.SkipDir
+		}
+		if err := s.watcher.Add(path); err != nil {
+			if errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE) {
+				s.reportError(fmt.Errorf("%w: cannot watch %s: %v", ErrWatchLimit, path, err))
+				return filepath.SkipAll
+			}
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		s.dirs[path] = true
+		return nil
+	})
+}
+
+// handleDirectory keeps the watch set in sync with the tree: new directories are watched,
+// together with their subdirectories, and deleted ones are dropped. Returns true if the
+// event was about a directory.
+func (s *directoryWatchSource) handleDirectory(ctx context.Context, event fsnotify.Event, emit bool) bool {
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		if !s.dirs[event.Name] {
+			return false
+		}
+		prefix := event.Name + string(filepath.Separator)
+		for dir := range s.dirs {
+			if dir == event.Name || strings.HasPrefix(dir, prefix) {
+				_ = s.watcher.Remove(dir) // Already gone when the directory was deleted.
+				delete(s.dirs, dir)
+			}
+		}
+		return true
+	}
+
+	if !event.Has(fsnotify.Create) {
+		return false
+	}
+	info, err := os.Stat(event.Name)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	if rel, err := filepath.Rel(s.repo.Path, event.Name); err == nil {
+		top := strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]
+		if top == ".git" || top == s.repo.config.SystemDir {
+			return true
+		}
+	}
+	if err := s.addTree(ctx, event.Name, emit); err != nil {
+		s.reportError(fmt.Errorf("failed to watch new directory %s: %w", event.Name, err))
+	}
+	return true
+}
+
+func (s *directoryWatchSource) reportError(err error) {
+	if s.repo.config.Logger != nil {
+		s.repo.config.Logger.Error("watcher error", "error", err)
+	}
+	if s.repo.config.ErrorHandler != nil {
+		s.repo.config.ErrorHandler(err)
 	}
 }
 
@@ -124,14 +282,26 @@ func (s *directoryWatchSource) handleGitLock(ctx context.Context, event fsnotify
 
 // processFsEvent filters, maps, and emits a standard filesystem event.
 func (s *directoryWatchSource) processFsEvent(ctx context.Context, event fsnotify.Event) {
-	if s.repo.shouldIgnore(event, s.pattern) {
+	if s.repo.isInternalPath(event.Name) {
 		return
 	}
-
 	eType := s.repo.mapEventType(event)
 	if eType == "" {
 		return
 	}
+	rel, err := filepath.Rel(s.repo.Path, event.Name)
+	if err != nil {
+		return
+	}
+	relPath := filepath.ToSlash(rel)
+
+	own := s.repo.isOwnWrite(event)
+	if isCollectionFile(relPath) {
+		s.emitRowChanges(ctx, event, relPath, !own)
+	}
+	if own || !s.repo.matchPattern(s.pattern, relPath) {
+		return
+	}
 
 	id, err := s.repo.resolveID(event.Name)
 	if err != nil {
@@ -145,9 +315,14 @@ func (s *directoryWatchSource) processFsEvent(ctx context.Context, event fsnotif
 		s.repo.config.Logger.Debug("fs event matched", "path", event.Name, "type", eType)
 	}
 
-	s.Emit(ctx, core.Event{
+	evt := core.Event{
 		Type:      eType,
 		ID:        id,
 		Timestamp: time.Now().Unix(),
-	})
+		Path:      relPath,
+	}
+	if s.repo.reportsLocal() {
+		s.repo.observeFromDisk(ctx, &evt, relPath, core.OriginExternal)
+	}
+	s.Emit(ctx, evt)
 }
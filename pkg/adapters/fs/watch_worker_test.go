@@ -0,0 +1,151 @@
+package fs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+func TestWatchNewDirectories(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := NewRepository(Config{
+		Path:      tmpDir,
+		Gitless:   true,
+		SystemDir: ".loam",
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	ctx := context.Background()
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+
+	watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer func() {
+		cancel()
+		time.Sleep(100 * time.Millisecond)
+	}()
+	events, err := repo.Watch(watchCtx, "**/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	waitFor := func(id string) {
+		t.Helper()
+		for {
+			select {
+			case e := <-events:
+				if e.ID == id {
+					if e.Type != core.EventCreate && e.Type != core.EventModify {
+						t.Errorf("unexpected event type for %s: %s", id, e.Type)
+					}
+					return
+				}
+			case <-watchCtx.Done():
+				t.Fatalf("timed out waiting for event on %s", id)
+			}
+		}
+	}
+
+	t.Run("Nested Directory", func(t *testing.T) {
+		dir := filepath.Join(tmpDir, "projects", "2024")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if err := os.WriteFile(filepath.Join(dir, "plan.md"), []byte("plan"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		waitFor("projects/2024/plan")
+	})
+
+	t.Run("Directory Moved In With Files", func(t *testing.T) {
+		outside := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(outside, "inbox", "deep"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(outside, "inbox", "deep", "idea.md"), []byte("idea"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filepath.Join(outside, "inbox"), filepath.Join(tmpDir, "inbox")); err != nil {
+			t.Skipf("cannot move across filesystems: %v", err)
+		}
+		waitFor("inbox/deep/idea")
+
+		// The moved tree is watched too.
+		if err := os.WriteFile(filepath.Join(tmpDir, "inbox", "deep", "later.md"), []byte("later"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		waitFor("inbox/deep/later")
+	})
+}
+
+func TestWatchPolling(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := NewRepository(Config{
+		Path:         tmpDir,
+		Gitless:      true,
+		SystemDir:    ".loam",
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Polling:      true,
+		PollInterval: 50 * time.Millisecond,
+	})
+	ctx := context.Background()
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "before.md"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer func() {
+		cancel()
+		time.Sleep(100 * time.Millisecond)
+	}()
+	events, err := repo.Watch(watchCtx, "notes/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	next := func() core.Event {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e
+		case <-watchCtx.Done():
+			t.Fatal("timed out waiting for event")
+		}
+		return core.Event{}
+	}
+
+	path := filepath.Join(tmpDir, "notes", "polled.md")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if e := next(); e.ID != "notes/polled" || e.Type != core.EventCreate {
+		t.Errorf("unexpected event: %+v", e)
+	}
+
+	// Writes by the repository itself are already indexed and not reported.
+	if err := repo.Save(ctx, core.Document{ID: "notes/own", Content: "mine"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if e := next(); e.ID != "notes/polled" || e.Type != core.EventDelete {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
@@ -0,0 +1,299 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// WorkspaceBranchPrefix is the git branch namespace used for workspaces.
+const WorkspaceBranchPrefix = "loam/workspace/"
+
+// activeWorkspaceFile stores the name of the active workspace inside the system directory.
+const activeWorkspaceFile = "workspace"
+
+var workspaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Workspaces manages branch-based workspaces (drafts, staging areas) of a repository.
+//
+// Each workspace is a git branch checked out into a linked worktree inside the system
+// directory (e.g. ".loam/workspaces/draft"), so the main working tree is never touched
+// until the workspace is merged.
+type Workspaces struct {
+	repo *Repository
+}
+
+// Workspaces returns the workspace manager for this repository.
+func (r *Repository) Workspaces() *Workspaces {
+	return &Workspaces{repo: r}
+}
+
+// Workspace implements core.Branchable.
+// It opens a repository bound to an existing workspace.
+func (r *Repository) Workspace(ctx context.Context, name string) (core.Repository, error) {
+	return r.Workspaces().Open(ctx, name)
+}
+
+func (w *Workspaces) check(name string) error {
+	if w.repo.config.Gitless {
+		return fmt.Errorf("workspaces require versioning (gitless mode)")
+	}
+	if !w.repo.git.IsRepo() {
+		return fmt.Errorf("path is not a git repository: %s", w.repo.Path)
+	}
+	if !workspaceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid workspace name %q", name)
+	}
+	return nil
+}
+
+// Path returns the directory where the workspace is checked out.
+func (w *Workspaces) Path(name string) string {
+	return filepath.Join(w.repo.Path, w.repo.config.SystemDir, "workspaces", name)
+}
+
+func (w *Workspaces) exists(name string) bool {
+	_, err := os.Stat(w.Path(name))
+	return err == nil
+}
+
+// Create creates a new workspace branching off the current HEAD.
+func (w *Workspaces) Create(ctx context.Context, name string) error {
+	if w.repo.config.ReadOnly {
+		return core.ErrReadOnly
+	}
+	if err := w.check(name); err != nil {
+		return err
+	}
+	if w.exists(name) {
+		return fmt.Errorf("workspace %q already exists", name)
+	}
+
+	unlock, err := w.repo.git.LockContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire git lock: %w", err)
+	}
+	defer unlock()
+
+	if err := os.MkdirAll(filepath.Dir(w.Path(name)), 0755); err != nil {
+		return fmt.Errorf("failed to create workspaces directory: %w", err)
+	}
+	if err := w.repo.git.AddWorktree(w.Path(name), WorkspaceBranchPrefix+name); err != nil {
+		return fmt.Errorf("failed to create workspace %q: %w", name, err)
+	}
+	return nil
+}
+
+// List returns the names of all workspaces.
+func (w *Workspaces) List(ctx context.Context) ([]string, error) {
+	if w.repo.config.Gitless {
+		return nil, nil
+	}
+	branches, err := w.repo.git.Branches(WorkspaceBranchPrefix + "*")
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(branches))
+	for _, b := range branches {
+		names = append(names, strings.TrimPrefix(b, WorkspaceBranchPrefix))
+	}
+	return names, nil
+}
+
+// Open returns a repository bound to the given workspace.
+// The returned repository shares the configuration (serializers, strictness, ID map) of the parent.
+func (w *Workspaces) Open(ctx context.Context, name string) (*Repository, error) {
+	if err := w.check(name); err != nil {
+		return nil, err
+	}
+	if !w.exists(name) {
+		return nil, fmt.Errorf("workspace %q does not exist", name)
+	}
+
+	config := w.repo.config
+	config.Path = w.Path(name)
+	config.AutoInit = false
+	config.MustExist = true
+
+	repo := NewRepository(config)
+	for ext, s := range w.repo.serializers {
+		repo.serializers[ext] = s
+	}
+	if err := repo.Initialize(ctx); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// Switch marks the given workspace as active. An empty name switches back to the main tree.
+// The active workspace is a hint for tools (like the CLI); the library does not switch implicitly.
+func (w *Workspaces) Switch(ctx context.Context, name string) error {
+	if w.repo.config.ReadOnly {
+		return core.ErrReadOnly
+	}
+	marker := filepath.Join(w.repo.Path, w.repo.config.SystemDir, activeWorkspaceFile)
+	if name == "" {
+		if err := os.Remove(marker); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := w.check(name); err != nil {
+		return err
+	}
+	if !w.exists(name) {
+		return fmt.Errorf("workspace %q does not exist", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(marker), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(marker, []byte(name+"\n"), 0644)
+}
+
+// Active returns the name of the active workspace, or an empty string for the main tree.
+func (w *Workspaces) Active() string {
+	return ActiveWorkspace(w.repo.Path, w.repo.config.SystemDir)
+}
+
+// ActiveWorkspace reads the active workspace marker of the vault at path.
+// Returns an empty string if no workspace is active.
+func ActiveWorkspace(path, systemDir string) string {
+	data, err := os.ReadFile(filepath.Join(path, systemDir, activeWorkspaceFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Merge publishes the workspace into the main tree with a single merge commit.
+// On conflict, the merge is aborted and the main tree is left untouched.
+// Active watchers receive an event for every document changed by the merge,
+// and the same events are returned to the caller.
+func (w *Workspaces) Merge(ctx context.Context, name string) ([]core.Event, error) {
+	if w.repo.config.ReadOnly {
+		return nil, core.ErrReadOnly
+	}
+	if err := w.check(name); err != nil {
+		return nil, err
+	}
+	if !w.exists(name) {
+		return nil, fmt.Errorf("workspace %q does not exist", name)
+	}
+
+	branch := WorkspaceBranchPrefix + name
+
+	unlock, err := w.repo.git.LockContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire git lock: %w", err)
+	}
+
+	changes, err := w.repo.git.ChangedFiles("HEAD", branch)
+	if err != nil {
+		unlock()
+		return nil, fmt.Errorf("failed to diff workspace %q: %w", name, err)
+	}
+	if len(changes) == 0 {
+		unlock()
+		return nil, nil
+	}
+
+	msg := "merge workspace " + name
+	if val, ok := ctx.Value(core.ChangeReasonKey).(string); ok && val != "" {
+		msg = val
+	}
+	// Git releases its index lock between merge phases, so the watcher would reconcile
+	// against a half checked-out tree. Pause it; watchers get the authoritative events below.
+	w.repo.pauseWatch.Add(1)
+	defer w.repo.pauseWatch.Add(-1)
+
+	// Previous versions for rich events, before the reconcile below refreshes the cache.
+	prevs := make(map[string]*indexEntry)
+	if w.repo.config.RichEvents {
+		for _, c := range changes {
+			prevs[c.Path] = w.repo.cache.Peek(c.Path)
+		}
+	}
+
+	if err := w.repo.git.MergeWith(branch, msg, commitOptions(ctx)); err != nil {
+		unlock()
+		return nil, fmt.Errorf("failed to merge workspace %q: %w", name, err)
+	}
+	unlock()
+
+	// Refresh the cache so later reconciliations do not report these changes again.
+	if _, _, err := w.repo.reconcile(ctx, core.OriginLocal, true); err != nil && w.repo.config.Logger != nil {
+		w.repo.config.Logger.Warn("failed to reconcile after workspace merge", "err", err)
+	}
+
+	now := time.Now().Unix()
+	var evts []core.Event
+	var paths []string
+	for _, c := range changes {
+		if strings.HasPrefix(filepath.Base(c.Path), ".") {
+			continue
+		}
+		id, err := w.repo.resolveID(filepath.Join(w.repo.Path, filepath.FromSlash(c.Path)))
+		if err != nil {
+			continue
+		}
+		e := core.Event{ID: id, Timestamp: now}
+		switch c.Status {
+		case "A":
+			e.Type = core.EventCreate
+		case "D":
+			e.Type = core.EventDelete
+		default:
+			e.Type = core.EventModify
+		}
+		if w.repo.config.RichEvents {
+			var doc *core.Document
+			if e.Type != core.EventDelete {
+				if d, err := w.repo.Get(ctx, id); err == nil {
+					doc = &d
+				}
+			}
+			w.repo.enrichEvent(&e, c.Path, prevs[c.Path], doc, core.OriginLocal)
+		}
+		evts = append(evts, e)
+		paths = append(paths, c.Path)
+	}
+
+	w.repo.broadcast(ctx, evts, paths)
+	return evts, nil
+}
+
+// Discard deletes the workspace and its branch, dropping all unmerged changes.
+func (w *Workspaces) Discard(ctx context.Context, name string) error {
+	if w.repo.config.ReadOnly {
+		return core.ErrReadOnly
+	}
+	if err := w.check(name); err != nil {
+		return err
+	}
+
+	unlock, err := w.repo.git.LockContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire git lock: %w", err)
+	}
+	defer unlock()
+
+	if w.exists(name) {
+		if err := w.repo.git.RemoveWorktree(w.Path(name)); err != nil {
+			return fmt.Errorf("failed to remove workspace %q: %w", name, err)
+		}
+	}
+	if err := w.repo.git.DeleteBranch(WorkspaceBranchPrefix + name); err != nil {
+		return fmt.Errorf("failed to delete workspace branch %q: %w", name, err)
+	}
+
+	if w.Active() == name {
+		_ = os.Remove(filepath.Join(w.repo.Path, w.repo.config.SystemDir, activeWorkspaceFile))
+	}
+	return nil
+}
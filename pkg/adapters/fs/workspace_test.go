@@ -0,0 +1,132 @@
+package fs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+func TestWorkspaces(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	tmpDir := t.TempDir()
+	repo := NewRepository(Config{
+		Path:      tmpDir,
+		AutoInit:  true,
+		SystemDir: ".loam",
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	ctx := context.Background()
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	if err := repo.Save(ctx, core.Document{ID: "existing", Content: "v1"}); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	ws := repo.Workspaces()
+	if err := ws.Create(ctx, "draft"); err != nil {
+		t.Fatalf("failed to create workspace: %v", err)
+	}
+
+	t.Run("Isolation", func(t *testing.T) {
+		draft, err := ws.Open(ctx, "draft")
+		if err != nil {
+			t.Fatalf("failed to open workspace: %v", err)
+		}
+		if err := draft.Save(ctx, core.Document{ID: "new-post", Content: "draft"}); err != nil {
+			t.Fatalf("failed to save in workspace: %v", err)
+		}
+		if err := draft.Save(ctx, core.Document{ID: "existing", Content: "v2"}); err != nil {
+			t.Fatalf("failed to save in workspace: %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(tmpDir, "new-post.md")); !os.IsNotExist(err) {
+			t.Error("workspace write leaked into the main tree")
+		}
+		doc, err := repo.Get(ctx, "existing")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Content != "v1" {
+			t.Errorf("main tree content changed before merge: %q", doc.Content)
+		}
+
+		names, err := ws.List(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != 1 || names[0] != "draft" {
+			t.Errorf("unexpected workspaces: %v", names)
+		}
+	})
+
+	t.Run("Merge Emits Events", func(t *testing.T) {
+		watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		events, err := repo.Watch(watchCtx, "**/*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		merged, err := ws.Merge(ctx, "draft")
+		if err != nil {
+			t.Fatalf("merge failed: %v", err)
+		}
+		if len(merged) != 2 {
+			t.Fatalf("expected 2 changed documents, got %v", merged)
+		}
+
+		doc, err := repo.Get(ctx, "existing")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Content != "v2" {
+			t.Errorf("expected merged content, got %q", doc.Content)
+		}
+
+		seen := map[string]core.EventType{}
+		for len(seen) < 2 {
+			select {
+			case e := <-events:
+				seen[e.ID] = e.Type
+			case <-watchCtx.Done():
+				t.Fatalf("timed out waiting for merge events, got %v", seen)
+			}
+		}
+		if seen["new-post"] != core.EventCreate || seen["existing"] != core.EventModify {
+			t.Errorf("unexpected events: %v", seen)
+		}
+	})
+
+	t.Run("Switch And Discard", func(t *testing.T) {
+		if err := ws.Switch(ctx, "draft"); err != nil {
+			t.Fatal(err)
+		}
+		if ws.Active() != "draft" {
+			t.Errorf("expected active workspace 'draft', got %q", ws.Active())
+		}
+		if err := ws.Switch(ctx, "missing"); err == nil {
+			t.Error("expected error switching to unknown workspace")
+		}
+
+		if err := ws.Discard(ctx, "draft"); err != nil {
+			t.Fatalf("discard failed: %v", err)
+		}
+		if ws.Active() != "" {
+			t.Error("discarding the active workspace should reset it")
+		}
+		if _, err := ws.Open(ctx, "draft"); err == nil {
+			t.Error("expected error opening discarded workspace")
+		}
+	})
+}
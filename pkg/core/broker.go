@@ -0,0 +1,272 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// DefaultEventBuffer is the per-subscriber buffer used when WithEventBuffer is not set.
+const DefaultEventBuffer = 100
+
+// OverflowPolicy decides what the broker does with an event for a subscriber
+// whose buffer is full.
+type OverflowPolicy string
+
+const (
+	OverflowBlock      OverflowPolicy = "block"       // Wait for the subscriber; slows down every subscriber.
+	OverflowDropOldest OverflowPolicy = "drop-oldest" // Discard the oldest buffered event.
+	OverflowDropNewest OverflowPolicy = "drop-newest" // Discard the new event.
+	OverflowDisconnect OverflowPolicy = "disconnect"  // Close the subscription with ErrSlowSubscriber.
+)
+
+// WithOverflowPolicy sets the default overflow policy of subscriptions (OverflowBlock if unset).
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(s *Service) {
+		s.overflowPolicy = policy
+	}
+}
+
+// SubscribeOption configures a single subscription.
+type SubscribeOption func(*Subscription)
+
+// SubscriberBuffer overrides the service's event buffer size for the subscription.
+func SubscriberBuffer(size int) SubscribeOption {
+	return func(sub *Subscription) {
+		sub.buffer = size
+	}
+}
+
+// SubscriberPolicy overrides the service's overflow policy for the subscription.
+func SubscriberPolicy(policy OverflowPolicy) SubscribeOption {
+	return func(sub *Subscription) {
+		sub.policy = policy
+	}
+}
+
+// Subscription is a subscriber of the service's event broker.
+type Subscription struct {
+	id      int
+	pattern string
+	buffer  int
+	policy  OverflowPolicy
+
+	events  chan Event
+	dropped atomic.Uint64
+
+	mu       sync.Mutex // Serializes deliveries with closing the channel.
+	closed   bool
+	err      error
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// Events returns the channel of events matching the subscription's pattern.
+// It is closed when the subscription ends.
+func (sub *Subscription) Events() <-chan Event {
+	return sub.events
+}
+
+// Err returns why the subscription ended early (e.g. ErrSlowSubscriber), or nil.
+func (sub *Subscription) Err() error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.err
+}
+
+func (sub *Subscription) matches(e Event) bool {
+	if sub.pattern == "" || sub.pattern == "*" {
+		return true
+	}
+	target := e.Path
+	if target == "" {
+		target = e.ID
+	}
+	ok, _ := doublestar.Match(sub.pattern, target)
+	return ok
+}
+
+// deliver hands e to the subscriber according to its policy. It returns false
+// if the subscriber must be disconnected.
+func (sub *Subscription) deliver(e Event) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return true
+	}
+
+	select {
+	case sub.events <- e:
+		return true
+	default:
+	}
+
+	switch sub.policy {
+	case OverflowDropNewest:
+		sub.dropped.Add(1)
+	case OverflowDropOldest:
+		for {
+			select {
+			case sub.events <- e:
+				return true
+			default:
+			}
+			select {
+			case <-sub.events:
+				sub.dropped.Add(1)
+			default:
+			}
+		}
+	case OverflowDisconnect:
+		sub.dropped.Add(1)
+		sub.err = ErrSlowSubscriber
+		return false
+	default:
+		select {
+		case sub.events <- e:
+		case <-sub.done:
+		}
+	}
+	return true
+}
+
+// close ends the subscription; done is closed first, so a blocked delivery gives up the lock.
+func (sub *Subscription) close() {
+	sub.doneOnce.Do(func() { close(sub.done) })
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if !sub.closed {
+		sub.closed = true
+		close(sub.events)
+	}
+}
+
+// SubscriberState is the observable state of a subscription.
+type SubscriberState struct {
+	ID      int            `json:"id"`
+	Pattern string         `json:"pattern"`
+	Policy  OverflowPolicy `json:"policy"`
+	Lag     int            `json:"lag"` // Events buffered but not yet received.
+	Buffer  int            `json:"buffer"`
+	Dropped uint64         `json:"dropped"`
+}
+
+// broker runs a single adapter watch and fans its events out to the subscriptions.
+// The watch starts with the first subscription and stops with the last one.
+type broker struct {
+	repo Watchable
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	nextID int
+	cancel context.CancelFunc // Stops the running adapter watch; nil if none.
+}
+
+func newBroker(repo Watchable) *broker {
+	return &broker{repo: repo, subs: make(map[*Subscription]struct{})}
+}
+
+func (b *broker) subscribe(ctx context.Context, sub *Subscription) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cancel == nil {
+		// The watch outlives the context of the subscription that started it.
+		watchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		events, err := b.repo.Watch(watchCtx, "**")
+		if err != nil {
+			cancel()
+			return err
+		}
+		b.cancel = cancel
+		go b.dispatch(watchCtx, events)
+	}
+
+	b.nextID++
+	sub.id = b.nextID
+	b.subs[sub] = struct{}{}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-sub.done:
+		}
+		b.unsubscribe(sub)
+	}()
+	return nil
+}
+
+// dispatch delivers the events of one adapter watch until it ends.
+func (b *broker) dispatch(ctx context.Context, events <-chan Event) {
+	for e := range events {
+		if ctx.Err() != nil {
+			continue // Stopped: drain without delivering to newer subscriptions.
+		}
+		for _, sub := range b.subscribers() {
+			if sub.matches(e) && !sub.deliver(e) {
+				b.unsubscribe(sub)
+			}
+		}
+	}
+
+	// The adapter watch ended: end its subscriptions, unless it was stopped on purpose
+	// and a new watch already serves newer ones.
+	b.mu.Lock()
+	if ctx.Err() != nil {
+		b.mu.Unlock()
+		return
+	}
+	b.cancel()
+	subs := b.subs
+	b.subs = make(map[*Subscription]struct{})
+	b.cancel = nil
+	b.mu.Unlock()
+	for sub := range subs {
+		sub.close()
+	}
+}
+
+func (b *broker) subscribers() []*Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs := make([]*Subscription, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+func (b *broker) unsubscribe(sub *Subscription) {
+	sub.close()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	if len(b.subs) == 0 && b.cancel != nil {
+		b.cancel()
+		b.cancel = nil
+	}
+}
+
+func (b *broker) state() []SubscriberState {
+	subs := b.subscribers()
+	sort.Slice(subs, func(i, j int) bool { return subs[i].id < subs[j].id })
+	states := make([]SubscriberState, 0, len(subs))
+	for _, sub := range subs {
+		states = append(states, SubscriberState{
+			ID:      sub.id,
+			Pattern: sub.pattern,
+			Policy:  sub.policy,
+			Lag:     len(sub.events),
+			Buffer:  sub.buffer,
+			Dropped: sub.dropped.Load(),
+		})
+	}
+	return states
+}
@@ -0,0 +1,138 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// countingRepository is a MockRepository counting the adapter watches it serves.
+type countingRepository struct {
+	*MockRepository
+	events chan core.Event
+
+	mu      sync.Mutex
+	watches []context.Context
+}
+
+func (c *countingRepository) Watch(ctx context.Context, pattern string) (<-chan core.Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.watches = append(c.watches, ctx)
+	return c.events, nil
+}
+
+func (c *countingRepository) watchCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.watches)
+}
+
+func receive(t *testing.T, ch <-chan core.Event) core.Event {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return core.Event{}
+}
+
+func TestService_Broker(t *testing.T) {
+	repo := &countingRepository{MockRepository: NewMockRepository(), events: make(chan core.Event)}
+	service := core.NewService(repo, core.WithEventBuffer(2))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The barrier receives everything; once it sees "sync", every earlier event
+	// has been handed to all subscribers.
+	barrier, err := service.Subscribe(ctx, "**", core.SubscriberBuffer(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscribe := func(policy core.OverflowPolicy) *core.Subscription {
+		sub, err := service.Subscribe(ctx, "notes/**", core.SubscriberPolicy(policy))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sub
+	}
+	newest := subscribe(core.OverflowDropNewest)
+	oldest := subscribe(core.OverflowDropOldest)
+	disconnect := subscribe(core.OverflowDisconnect)
+	others, err := service.Watch(ctx, "pages/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := repo.watchCount(); n != 1 {
+		t.Fatalf("expected a single adapter watch, got %d", n)
+	}
+
+	for i := 1; i <= 4; i++ {
+		repo.events <- core.Event{Type: core.EventModify, ID: fmt.Sprintf("notes/%d", i), Path: fmt.Sprintf("notes/%d.md", i)}
+	}
+	repo.events <- core.Event{Type: core.EventCreate, ID: "pages/about", Path: "pages/about.md"}
+	repo.events <- core.Event{ID: "sync", Path: "sync"}
+	for {
+		if e := receive(t, barrier.Events()); e.ID == "sync" {
+			break
+		}
+	}
+
+	state := service.State().(core.ServiceState)
+	if len(state.Subscribers) != 4 {
+		t.Fatalf("expected 4 subscribers, got %+v", state.Subscribers)
+	}
+	if s := state.Subscribers[1]; s.Policy != core.OverflowDropNewest || s.Lag != 2 || s.Buffer != 2 || s.Dropped != 2 {
+		t.Errorf("unexpected state: %+v", s)
+	}
+
+	t.Run("Drop Newest", func(t *testing.T) {
+		for _, want := range []string{"notes/1", "notes/2"} {
+			if e := receive(t, newest.Events()); e.ID != want {
+				t.Errorf("expected %s, got %s", want, e.ID)
+			}
+		}
+	})
+
+	t.Run("Drop Oldest", func(t *testing.T) {
+		for _, want := range []string{"notes/3", "notes/4"} {
+			if e := receive(t, oldest.Events()); e.ID != want {
+				t.Errorf("expected %s, got %s", want, e.ID)
+			}
+		}
+	})
+
+	t.Run("Disconnect", func(t *testing.T) {
+		for range disconnect.Events() {
+		}
+		if !errors.Is(disconnect.Err(), core.ErrSlowSubscriber) {
+			t.Errorf("expected ErrSlowSubscriber, got %v", disconnect.Err())
+		}
+	})
+
+	t.Run("Pattern", func(t *testing.T) {
+		if e := receive(t, others); e.ID != "pages/about" {
+			t.Errorf("unexpected event: %+v", e)
+		}
+	})
+
+	t.Run("Stops With Last Subscriber", func(t *testing.T) {
+		cancel()
+		select {
+		case <-repo.watches[0].Done():
+		case <-time.After(2 * time.Second):
+			t.Fatal("adapter watch still running")
+		}
+	})
+}
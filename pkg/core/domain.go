@@ -1,7 +1,11 @@
 // Document is the central entity of the domain.
 package core
 
-import "fmt"
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
 
 // Metadata represents the flexible key-value pairs associated with a document.
 type Metadata map[string]any
@@ -23,18 +27,140 @@ const (
 	EventDelete EventType = "DELETE"
 )
 
+// EventOrigin tells whether a change was made by this process or externally.
+type EventOrigin string
+
+const (
+	OriginLocal    EventOrigin = "local"    // Made through this process (Save, Delete, transactions, merges).
+	OriginExternal EventOrigin = "external" // Made by another process, an editor or a git operation.
+)
+
+// FieldChange is a metadata field that differs between two versions of a document.
+type FieldChange struct {
+	Field string
+	Old   any // nil if the field was added.
+	New   any // nil if the field was removed.
+}
+
 // Event represents a change in the vault.
 type Event struct {
 	Type      EventType
 	ID        string
 	Timestamp int64 // Unix timestamp
+
+	// Path is the file the change was observed on, relative to the vault and
+	// slash-separated (the row ID for collection rows). Watch patterns are matched
+	// against it; empty if the adapter does not report it.
+	Path string
+
+	// Rich payload, only populated when the adapter has rich events enabled
+	// (see loam.WithRichEvents). Commit and Origin are also kept in the change log.
+	Document *Document     // New version of the document (nil for deletes).
+	Previous *Document     // Previous version as known to the index (metadata only; nil if new).
+	Diff     []FieldChange // Metadata changes from Previous to Document, sorted by field.
+	Commit   string        // Hash of the commit that produced the change, if known.
+	Origin   EventOrigin
+
+	// Cursor is the position of the change in the repository's change log
+	// (see ChangeLog); zero if the change was not logged.
+	Cursor Cursor
 }
 
+// Cursor is a position in a change log: the sequence number of the last change seen.
+// The zero Cursor is the beginning of the log.
+type Cursor uint64
+
 func (e Event) String() string {
 	return fmt.Sprintf("%s %s", e.Type, e.ID)
 }
 
+// DiffMetadata returns the top-level fields that differ between two metadata maps, sorted by field.
+func DiffMetadata(old, new Metadata) []FieldChange {
+	var changes []FieldChange
+	for k, ov := range old {
+		nv, ok := new[k]
+		if !ok {
+			changes = append(changes, FieldChange{Field: k, Old: ov})
+		} else if !reflect.DeepEqual(ov, nv) {
+			changes = append(changes, FieldChange{Field: k, Old: ov, New: nv})
+		}
+	}
+	for k, nv := range new {
+		if _, ok := old[k]; !ok {
+			changes = append(changes, FieldChange{Field: k, New: nv})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// SignatureStatus is the verification result of a revision's signature.
+type SignatureStatus string
+
+const (
+	SignatureGood      SignatureStatus = "good"      // Valid signature from a trusted key.
+	SignatureUntrusted SignatureStatus = "untrusted" // Valid signature, but the key is not trusted (or unknown).
+	SignatureExpired   SignatureStatus = "expired"   // Valid signature made with an expired key or signature.
+	SignatureBad       SignatureStatus = "bad"       // Invalid signature or revoked key.
+	SignatureUnsigned  SignatureStatus = "unsigned"  // No signature.
+)
+
+// Revision is a single version in the history of a document.
+type Revision struct {
+	Hash        string
+	Author      string
+	AuthorEmail string
+	Timestamp   int64 // Unix timestamp
+	Message     string
+	Signature   SignatureStatus
+	Signer      string // Identity of the signer, when known.
+}
+
 // ChangeReasonKey is the context key for passing the commit message/change reason.
 type contextKey string
 
 const ChangeReasonKey contextKey = "change_reason"
+
+// ChangeInfoKey is the context key for passing a ChangeInfo (commit attribution).
+const ChangeInfoKey contextKey = "change_info"
+
+// ChangeInfo carries attribution for a change, complementing the change reason.
+// Adapters with versioning apply it to the commits they produce (e.g. git --author and trailers).
+type ChangeInfo struct {
+	AuthorName  string
+	AuthorEmail string
+	CoAuthors   []string          // "Name <email>", rendered as Co-authored-by trailers.
+	Trailers    map[string]string // Arbitrary trailers (e.g. "Ticket": "OPS-42", "Request-Id": "abc").
+}
+
+// Author returns the author in "Name <email>" form, or an empty string if unset.
+// If only the email is known, it is also used as the name.
+func (c ChangeInfo) Author() string {
+	if c.AuthorName == "" && c.AuthorEmail == "" {
+		return ""
+	}
+	name := c.AuthorName
+	if name == "" {
+		name = c.AuthorEmail
+	}
+	return fmt.Sprintf("%s <%s>", name, c.AuthorEmail)
+}
+
+// TrailerLines returns the trailers as "Key: value" lines in a deterministic order,
+// followed by one Co-authored-by line per co-author.
+func (c ChangeInfo) TrailerLines() []string {
+	keys := make([]string, 0, len(c.Trailers))
+	for k := range c.Trailers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys)+len(c.CoAuthors))
+	for _, k := range keys {
+		lines = append(lines, k+": "+c.Trailers[k])
+	}
+	for _, co := range c.CoAuthors {
+		lines = append(lines, "Co-authored-by: "+co)
+	}
+	return lines
+}
@@ -1,8 +1,57 @@
 package core
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // Common errors.
 var (
 	ErrReadOnly = errors.New("repository is in read-only mode")
+	// ErrConflict is returned by Transaction.Commit when a document read by the
+	// transaction was changed by another writer. Retrying the transaction is safe.
+	ErrConflict = errors.New("transaction conflict")
+	// ErrSlowSubscriber is reported by Subscription.Err when the subscription was
+	// disconnected for falling behind (see OverflowDisconnect).
+	ErrSlowSubscriber = errors.New("subscriber too slow, disconnected")
+	// ErrAborted is returned by next to the commit hooks of a prepared transaction
+	// when the two-phase commit is rolled back (e.g. another participant failed).
+	ErrAborted = errors.New("transaction aborted")
 )
+
+// ErrInvalidDocument matches every ValidationError (errors.Is).
+var ErrInvalidDocument = errors.New("invalid document")
+
+// FieldError is a problem with one field of a document.
+type FieldError struct {
+	Field   string `json:"field"` // Dotted path (e.g. "author.name"); empty for the document as a whole.
+	Message string `json:"message"`
+}
+
+func (e FieldError) String() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError rejects a document, listing what is wrong with it.
+// Hooks and validators return it to report structured, per-field errors.
+type ValidationError struct {
+	ID     string       `json:"id"`
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		parts[i] = fe.String()
+	}
+	return fmt.Sprintf("invalid document %s: %s", e.ID, strings.Join(parts, "; "))
+}
+
+// Is reports whether target is ErrInvalidDocument.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidDocument
+}
@@ -0,0 +1,231 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// MutationKind identifies the write a hook intercepts.
+type MutationKind string
+
+const (
+	MutationSave   MutationKind = "save"
+	MutationDelete MutationKind = "delete"
+	MutationCommit MutationKind = "commit"
+	// MutationReconcile carries the changes Reconcile found on storage (e.g. offline
+	// edits). They are already applied: next detects them and fills Changes.
+	MutationReconcile MutationKind = "reconcile"
+)
+
+// Mutation is a write on its way to the repository, as seen by hooks.
+type Mutation struct {
+	Kind MutationKind
+
+	// Document is the document to save, which hooks may modify (e.g. set
+	// "updated_at" or slugify the ID). For deletes only the ID is set.
+	Document Document
+	// Staged is set for saves and deletes inside a transaction: next only stages
+	// the change, which is applied by the commit.
+	Staged bool
+
+	// Message is the commit message, which hooks may modify.
+	Message string
+	// Changes holds the saves and deletes staged by the transaction, in order, or
+	// those found by Reconcile.
+	Changes []Mutation
+}
+
+// MutationHandler applies a mutation.
+type MutationHandler func(ctx context.Context, m *Mutation) error
+
+// Hook is a middleware around the writes of a Service. It may inspect or modify m,
+// reject it by returning an error (see ValidationError) without calling next, or
+// act after next succeeds (e.g. after-commit side effects).
+type Hook func(ctx context.Context, m *Mutation, next MutationHandler) error
+
+// WithHook adds hooks around SaveDocument, DeleteDocument, transactions (their
+// saves, deletes and commit) and Reconcile. The first hook registered runs outermost.
+func WithHook(hooks ...Hook) Option {
+	return func(s *Service) {
+		s.hooks = append(s.hooks, hooks...)
+	}
+}
+
+// apply runs m through the hook chain, ending with final.
+func (s *Service) apply(ctx context.Context, m *Mutation, final MutationHandler) error {
+	h := final
+	for i := len(s.hooks) - 1; i >= 0; i-- {
+		hook, next := s.hooks[i], h
+		h = func(ctx context.Context, m *Mutation) error {
+			return hook(ctx, m, next)
+		}
+	}
+	return h(ctx, m)
+}
+
+// hookedTransaction runs the service's hooks around a repository transaction.
+type hookedTransaction struct {
+	Transaction
+	s *Service
+
+	mu      sync.Mutex
+	changes []Mutation
+	marks   []savepointMark
+
+	// Set by Prepare: the commit hooks are suspended in next until the outcome
+	// of the two-phase commit is sent, and their result is sent on done.
+	prepared bool
+	message  string // Commit message, as left by the hooks on Prepare.
+	outcome  chan error
+	done     chan error
+}
+
+type savepointMark struct {
+	name    string
+	changes int
+}
+
+// unhooked returns the repository transaction behind the service's hooks.
+func unhooked(tx Transaction) Transaction {
+	if h, ok := tx.(*hookedTransaction); ok {
+		return h.Transaction
+	}
+	return tx
+}
+
+func (t *hookedTransaction) Save(ctx context.Context, doc Document) error {
+	m := &Mutation{Kind: MutationSave, Document: doc, Staged: true}
+	return t.s.apply(ctx, m, func(ctx context.Context, m *Mutation) error {
+		if m.Document.ID == "" {
+			return errors.New("document ID cannot be empty")
+		}
+		if err := t.Transaction.Save(ctx, m.Document); err != nil {
+			return err
+		}
+		t.record(*m)
+		return nil
+	})
+}
+
+func (t *hookedTransaction) Delete(ctx context.Context, id string) error {
+	m := &Mutation{Kind: MutationDelete, Document: Document{ID: id}, Staged: true}
+	return t.s.apply(ctx, m, func(ctx context.Context, m *Mutation) error {
+		if err := t.Transaction.Delete(ctx, m.Document.ID); err != nil {
+			return err
+		}
+		t.record(*m)
+		return nil
+	})
+}
+
+func (t *hookedTransaction) record(m Mutation) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.changes = append(t.changes, m)
+}
+
+func (t *hookedTransaction) Savepoint(ctx context.Context, name string) error {
+	if err := t.Transaction.Savepoint(ctx, name); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.marks = append(t.marks, savepointMark{name: name, changes: len(t.changes)})
+	return nil
+}
+
+func (t *hookedTransaction) RollbackTo(ctx context.Context, name string) error {
+	if err := t.Transaction.RollbackTo(ctx, name); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(t.marks) - 1; i >= 0; i-- {
+		if t.marks[i].name == name {
+			t.changes = t.changes[:t.marks[i].changes]
+			t.marks = t.marks[:i+1]
+			break
+		}
+	}
+	return nil
+}
+
+func (t *hookedTransaction) commitMutation(msg string) *Mutation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &Mutation{Kind: MutationCommit, Message: msg, Changes: append([]Mutation(nil), t.changes...)}
+}
+
+func (t *hookedTransaction) Commit(ctx context.Context, msg string) error {
+	if t.prepared {
+		// The decision is made: commit, then resume the hooks after next.
+		err := t.Transaction.Commit(ctx, t.message)
+		if hookErr := t.resume(err); err == nil {
+			return hookErr
+		}
+		return err
+	}
+	return t.s.apply(ctx, t.commitMutation(msg), func(ctx context.Context, m *Mutation) error {
+		return t.Transaction.Commit(ctx, m.Message)
+	})
+}
+
+func (t *hookedTransaction) Rollback(ctx context.Context) error {
+	err := t.Transaction.Rollback(ctx)
+	if t.prepared {
+		t.resume(ErrAborted)
+	}
+	return err
+}
+
+// Prepare implements Preparable. In a two-phase commit the commit hooks run up to
+// next on Prepare, so they can still veto every participant. next returns once
+// the transaction is committed or rolled back, so what the hooks do after it
+// (e.g. after-commit side effects) only runs when the global commit succeeds.
+func (t *hookedTransaction) Prepare(ctx context.Context, msg string, coord Coordination) error {
+	p, ok := t.Transaction.(Preparable)
+	if !ok {
+		return errors.New("repository does not support two-phase commit")
+	}
+
+	prepared := make(chan error, 1)
+	outcome := make(chan error, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- t.s.apply(ctx, t.commitMutation(msg), func(ctx context.Context, m *Mutation) error {
+			if err := p.Prepare(ctx, m.Message, coord); err != nil {
+				prepared <- err
+				return err
+			}
+			t.message = m.Message
+			prepared <- nil
+			return <-outcome
+		})
+	}()
+
+	select {
+	case err := <-prepared:
+		if err != nil {
+			if hookErr := <-done; hookErr != nil {
+				return hookErr
+			}
+			return err
+		}
+		t.prepared, t.outcome, t.done = true, outcome, done
+		return nil
+	case err := <-done:
+		if err == nil {
+			err = errors.New("commit hooks returned without preparing the transaction")
+		}
+		return err
+	}
+}
+
+// resume ends the commit hooks suspended by Prepare, with err as the result of
+// next, and returns their result.
+func (t *hookedTransaction) resume(err error) error {
+	t.prepared = false
+	t.outcome <- err
+	return <-t.done
+}
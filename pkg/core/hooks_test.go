@@ -0,0 +1,144 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// transactionalRepository is a MockRepository with transactions staged in memory.
+type transactionalRepository struct {
+	*MockRepository
+	messages []string
+}
+
+func (r *transactionalRepository) Begin(ctx context.Context) (core.Transaction, error) {
+	return &mockTransaction{repo: r, staged: make(map[string]*core.Document)}, nil
+}
+
+type mockTransaction struct {
+	core.Transaction // Methods not used by the tests.
+	repo             *transactionalRepository
+	staged           map[string]*core.Document // nil value: deleted
+}
+
+func (tx *mockTransaction) Save(ctx context.Context, doc core.Document) error {
+	tx.staged[doc.ID] = &doc
+	return nil
+}
+
+func (tx *mockTransaction) Delete(ctx context.Context, id string) error {
+	tx.staged[id] = nil
+	return nil
+}
+
+func (tx *mockTransaction) Commit(ctx context.Context, msg string) error {
+	for id, doc := range tx.staged {
+		if doc == nil {
+			delete(tx.repo.docs, id)
+		} else {
+			tx.repo.docs[id] = *doc
+		}
+	}
+	tx.repo.messages = append(tx.repo.messages, msg)
+	return nil
+}
+
+func (tx *mockTransaction) Rollback(ctx context.Context) error { return nil }
+
+func TestService_Hooks(t *testing.T) {
+	repo := &transactionalRepository{MockRepository: NewMockRepository()}
+	var trace []string
+	var committed [][]core.Mutation
+
+	stamp := func(ctx context.Context, m *core.Mutation, next core.MutationHandler) error {
+		trace = append(trace, "stamp:"+string(m.Kind))
+		if m.Kind == core.MutationSave {
+			m.Document.ID = strings.ToLower(strings.ReplaceAll(m.Document.ID, " ", "-"))
+			if m.Document.Metadata == nil {
+				m.Document.Metadata = core.Metadata{}
+			}
+			m.Document.Metadata["updated_at"] = "2026-01-01"
+		}
+		return next(ctx, m)
+	}
+	validate := func(ctx context.Context, m *core.Mutation, next core.MutationHandler) error {
+		trace = append(trace, "validate:"+string(m.Kind))
+		if m.Kind == core.MutationSave && m.Document.Metadata["title"] == nil {
+			return &core.ValidationError{ID: m.Document.ID, Errors: []core.FieldError{{Field: "title", Message: "is required"}}}
+		}
+		if m.Kind == core.MutationDelete && m.Document.ID == "protected" {
+			return errors.New("protected document")
+		}
+		if err := next(ctx, m); err != nil {
+			return err
+		}
+		if m.Kind == core.MutationCommit {
+			committed = append(committed, m.Changes) // After-commit side effect.
+		}
+		return nil
+	}
+	service := core.NewService(repo, core.WithHook(stamp), core.WithHook(validate))
+	ctx := context.Background()
+
+	t.Run("Mutate", func(t *testing.T) {
+		if err := service.SaveDocument(ctx, "Hello World", "", core.Metadata{"title": "Hi"}); err != nil {
+			t.Fatal(err)
+		}
+		doc, ok := repo.docs["hello-world"]
+		if !ok || doc.Metadata["updated_at"] != "2026-01-01" {
+			t.Errorf("expected stamped document, got %+v", repo.docs)
+		}
+		if strings.Join(trace, ",") != "stamp:save,validate:save" {
+			t.Errorf("unexpected hook order: %v", trace)
+		}
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		err := service.SaveDocument(ctx, "untitled", "", nil)
+		var verr *core.ValidationError
+		if !errors.As(err, &verr) || !errors.Is(err, core.ErrInvalidDocument) || verr.Errors[0].Field != "title" {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+		if _, ok := repo.docs["untitled"]; ok {
+			t.Error("rejected document was saved")
+		}
+
+		repo.docs["protected"] = core.Document{ID: "protected"}
+		if err := service.DeleteDocument(ctx, "protected"); err == nil {
+			t.Error("expected delete to be rejected")
+		}
+	})
+
+	t.Run("Transaction", func(t *testing.T) {
+		trace = nil
+		err := service.WithTransaction(ctx, func(tx core.Transaction) error {
+			if err := tx.Save(ctx, core.Document{ID: "Tx Doc", Metadata: core.Metadata{"title": "T"}}); err != nil {
+				return err
+			}
+			return tx.Delete(ctx, "hello-world")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := repo.docs["tx-doc"]; !ok {
+			t.Errorf("expected staged save to go through hooks, got %+v", repo.docs)
+		}
+		if len(committed) != 1 || len(committed[0]) != 2 || !committed[0][0].Staged || committed[0][1].Kind != core.MutationDelete {
+			t.Errorf("unexpected committed changes: %+v", committed)
+		}
+
+		err = service.WithTransaction(ctx, func(tx core.Transaction) error {
+			return tx.Save(ctx, core.Document{ID: "bad"})
+		})
+		if !errors.Is(err, core.ErrInvalidDocument) {
+			t.Errorf("expected validation error from transaction, got %v", err)
+		}
+		if len(repo.messages) != 1 {
+			t.Errorf("rejected transaction was committed: %v", repo.messages)
+		}
+	})
+}
@@ -6,8 +6,9 @@ import (
 
 // ServiceState exposes internal state for observability.
 type ServiceState struct {
-	EventBufferSize int    `json:"event_buffer_size"`
-	RepositoryType  string `json:"repository_type"`
+	EventBufferSize int               `json:"event_buffer_size"`
+	RepositoryType  string            `json:"repository_type"`
+	Subscribers     []SubscriberState `json:"subscribers,omitempty"`
 }
 
 // State implements introspection.Introspectable.
@@ -24,9 +25,15 @@ func (s *Service) State() any {
 		}
 	}
 
+	var subscribers []SubscriberState
+	if s.broker != nil {
+		subscribers = s.broker.state()
+	}
+
 	return ServiceState{
 		EventBufferSize: s.eventBufferSize,
 		RepositoryType:  repoType,
+		Subscribers:     subscribers,
 	}
 }
 
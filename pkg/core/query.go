@@ -0,0 +1,215 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Operator is a comparison used by a query condition.
+type Operator string
+
+const (
+	OpEq       Operator = "="
+	OpNe       Operator = "!="
+	OpLt       Operator = "<"
+	OpLte      Operator = "<="
+	OpGt       Operator = ">"
+	OpGte      Operator = ">="
+	OpContains Operator = "contains" // Substring of a string, or element of a list.
+	OpExists   Operator = "exists"   // Field is present (Value is ignored).
+)
+
+// IDField is the field name addressing the document ID in conditions and OrderBy.
+// It is not a plain name, so that a metadata field "id" stays addressable.
+const IDField = "@id"
+
+// Condition filters documents on a metadata field.
+// Nested fields are addressed with dots (e.g. "author.name"); IDField refers to the document ID.
+type Condition struct {
+	Field string
+	Op    Operator
+	Value any
+}
+
+// Query selects documents by ID pattern and metadata conditions.
+// The zero Query matches every document.
+type Query struct {
+	Pattern string      // Glob on the document ID (e.g. "invoices/**"). Empty matches all.
+	Where   []Condition // All conditions must hold.
+	OrderBy string      // Field to sort by; prefix with "-" for descending order.
+	Limit   int         // Maximum number of results; 0 means no limit.
+}
+
+// Match reports whether the document satisfies the pattern and all conditions.
+func (q Query) Match(doc Document) bool {
+	if q.Pattern != "" && q.Pattern != "*" {
+		if ok, _ := doublestar.Match(q.Pattern, doc.ID); !ok {
+			return false
+		}
+	}
+	for _, c := range q.Where {
+		if !c.Match(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply filters, sorts and limits docs according to the query.
+func (q Query) Apply(docs []Document) []Document {
+	var out []Document
+	for _, doc := range docs {
+		if q.Match(doc) {
+			out = append(out, doc)
+		}
+	}
+
+	if q.OrderBy != "" {
+		field, desc := strings.CutPrefix(q.OrderBy, "-")
+		sort.SliceStable(out, func(i, j int) bool {
+			a, aok := lookupField(out[i], field)
+			b, bok := lookupField(out[j], field)
+			// Documents missing the field always go last.
+			if !aok || !bok {
+				return aok && !bok
+			}
+			cmp, ok := compareValues(a, b)
+			if !ok {
+				return false
+			}
+			if desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		})
+	}
+
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out
+}
+
+// Match reports whether the document satisfies the condition.
+func (c Condition) Match(doc Document) bool {
+	v, ok := lookupField(doc, c.Field)
+	if c.Op == OpExists {
+		return ok
+	}
+	if !ok {
+		return c.Op == OpNe
+	}
+
+	switch c.Op {
+	case OpEq, OpNe:
+		cmp, ok := compareValues(v, c.Value)
+		eq := ok && cmp == 0
+		if !ok {
+			eq = reflect.DeepEqual(v, c.Value)
+		}
+		return eq == (c.Op == OpEq)
+	case OpLt, OpLte, OpGt, OpGte:
+		cmp, ok := compareValues(v, c.Value)
+		if !ok {
+			return false
+		}
+		switch c.Op {
+		case OpLt:
+			return cmp < 0
+		case OpLte:
+			return cmp <= 0
+		case OpGt:
+			return cmp > 0
+		default:
+			return cmp >= 0
+		}
+	case OpContains:
+		if s, ok := v.(string); ok {
+			return strings.Contains(s, fmt.Sprint(c.Value))
+		}
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			for i := 0; i < rv.Len(); i++ {
+				if cmp, ok := compareValues(rv.Index(i).Interface(), c.Value); ok && cmp == 0 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// lookupField resolves a (possibly dotted) field in the document metadata.
+func lookupField(doc Document, field string) (any, bool) {
+	if field == IDField {
+		return doc.ID, true
+	}
+	var cur any = map[string]any(doc.Metadata)
+	for _, part := range strings.Split(field, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			if md, isMd := cur.(Metadata); isMd {
+				m = md
+			} else {
+				return nil, false
+			}
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// compareValues orders two values: numbers numerically, everything else
+// by its string form. ok is false for values that cannot be ordered (e.g. maps).
+func compareValues(a, b any) (int, bool) {
+	if af, aok := toFloat(a); aok {
+		if bf, bok := toFloat(b); bok {
+			switch {
+			case af < bf:
+				return -1, true
+			case af > bf:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	if !scalar(a) || !scalar(b) {
+		return 0, false
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b)), true
+}
+
+func toFloat(v any) (float64, bool) {
+	if n, ok := v.(json.Number); ok { // Numbers of strict mode.
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func scalar(v any) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Invalid:
+		// time.Time and other Stringers are still comparable by their string form.
+		_, ok := v.(fmt.Stringer)
+		return ok
+	}
+	return true
+}
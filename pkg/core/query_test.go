@@ -0,0 +1,57 @@
+package core_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+func TestQuery(t *testing.T) {
+	docs := []core.Document{
+		{ID: "posts/a", Metadata: core.Metadata{"views": 10, "tags": []any{"go", "db"}, "author": map[string]any{"name": "ana"}}},
+		{ID: "posts/b", Metadata: core.Metadata{"views": 30.0, "draft": true, "score": json.Number("9")}},
+		{ID: "posts/c", Metadata: core.Metadata{"views": 20, "score": json.Number("10")}},
+		{ID: "pages/about", Metadata: core.Metadata{"views": 99, "id": "posts/a"}},
+	}
+
+	ids := func(docs []core.Document) []string {
+		var out []string
+		for _, d := range docs {
+			out = append(out, d.ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		query core.Query
+		want  []string
+	}{
+		{"Zero Query", core.Query{}, []string{"posts/a", "posts/b", "posts/c", "pages/about"}},
+		{"Pattern", core.Query{Pattern: "posts/*"}, []string{"posts/a", "posts/b", "posts/c"}},
+		{"Numeric Comparison", core.Query{Where: []core.Condition{{Field: "views", Op: core.OpGt, Value: 15}}}, []string{"posts/b", "posts/c", "pages/about"}},
+		{"Exists", core.Query{Where: []core.Condition{{Field: "draft", Op: core.OpExists}}}, []string{"posts/b"}},
+		{"Not Equal Includes Missing", core.Query{Where: []core.Condition{{Field: "draft", Op: core.OpNe, Value: true}}}, []string{"posts/a", "posts/c", "pages/about"}},
+		{"Contains Element", core.Query{Where: []core.Condition{{Field: "tags", Op: core.OpContains, Value: "db"}}}, []string{"posts/a"}},
+		{"Nested Field", core.Query{Where: []core.Condition{{Field: "author.name", Op: core.OpEq, Value: "ana"}}}, []string{"posts/a"}},
+		{"Strict Numbers", core.Query{Where: []core.Condition{{Field: "score", Op: core.OpGt, Value: 9}}}, []string{"posts/c"}},
+		{"Document ID", core.Query{Where: []core.Condition{{Field: core.IDField, Op: core.OpEq, Value: "posts/a"}}}, []string{"posts/a"}},
+		{"Metadata ID", core.Query{Where: []core.Condition{{Field: "id", Op: core.OpEq, Value: "posts/a"}}}, []string{"pages/about"}},
+		{"Order And Limit", core.Query{Pattern: "posts/*", OrderBy: "-views", Limit: 2}, []string{"posts/b", "posts/c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ids(tt.query.Apply(docs))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -47,6 +47,30 @@ type Reconcilable interface {
 	Reconcile(ctx context.Context) ([]Event, error)
 }
 
+// ChangeLog defines an interface for repositories that keep a durable, ordered log of
+// applied changes, so consumers can resume where they stopped.
+type ChangeLog interface {
+	// Changes returns the logged changes after the cursor, oldest first.
+	// Each event carries its own Cursor; pass the last one back to resume.
+	Changes(ctx context.Context, since Cursor) ([]Event, error)
+	// WatchFrom replays the changes after the cursor that match the pattern,
+	// then delivers new changes as they are logged.
+	WatchFrom(ctx context.Context, since Cursor, pattern string) (<-chan Event, error)
+}
+
+// Branchable defines an interface for repositories that support isolated workspaces
+// (e.g. drafts or staging areas backed by git branches).
+type Branchable interface {
+	// Workspace opens a repository bound to an existing workspace.
+	Workspace(ctx context.Context, name string) (Repository, error)
+}
+
+// Versioned defines an interface for repositories that keep a history of document revisions.
+type Versioned interface {
+	// History returns the revisions of a document, newest first.
+	History(ctx context.Context, id string) ([]Revision, error)
+}
+
 // Transaction represents a unit of work (batch of operations).
 type Transaction interface {
 	// Save stages a document for saving.
@@ -55,7 +79,18 @@ type Transaction interface {
 	Get(ctx context.Context, id string) (Document, error)
 	// Delete stages a document for deletion.
 	Delete(ctx context.Context, id string) error
-	// Commit applies the changes.
+	// List returns all documents as seen by the transaction: the repository
+	// listing with staged saves and deletes applied on top.
+	List(ctx context.Context) ([]Document, error)
+	// Query returns the documents matching q, including staged changes.
+	Query(ctx context.Context, q Query) ([]Document, error)
+	// Savepoint marks the current staged state under name.
+	Savepoint(ctx context.Context, name string) error
+	// RollbackTo discards the changes staged since the most recent savepoint with
+	// the given name. The savepoint itself is kept and can be rolled back to again.
+	RollbackTo(ctx context.Context, name string) error
+	// Commit applies the changes. It fails with ErrConflict if a document
+	// read through Get was changed by another writer in the meantime.
 	Commit(ctx context.Context, msg string) error
 	// Rollback discards the changes.
 	Rollback(ctx context.Context) error
@@ -3,13 +3,19 @@ package core
 import (
 	"context"
 	"errors" // Added errors import
+	"fmt"
+	"math/rand/v2"
+	"reflect"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Option defines a functional option for configuring the Service.
 type Option func(*Service)
 
-// WithEventBuffer sets the buffer size for the Watch event broker.
+// WithEventBuffer sets the per-subscriber buffer size of the Watch event broker
+// (DefaultEventBuffer if unset).
 func WithEventBuffer(size int) Option {
 	return func(s *Service) {
 		s.eventBufferSize = size
@@ -20,6 +26,9 @@ func WithEventBuffer(size int) Option {
 type Service struct {
 	repo            Repository
 	eventBufferSize int
+	overflowPolicy  OverflowPolicy
+	broker          *broker
+	hooks           []Hook
 	mu              sync.RWMutex // protects fields for observability
 }
 
@@ -49,7 +58,12 @@ func (s *Service) SaveDocument(ctx context.Context, id string, content string, m
 		Metadata: metadata,
 	}
 
-	return s.repo.Save(ctx, doc)
+	return s.apply(ctx, &Mutation{Kind: MutationSave, Document: doc}, func(ctx context.Context, m *Mutation) error {
+		if m.Document.ID == "" {
+			return errors.New("document ID cannot be empty")
+		}
+		return s.repo.Save(ctx, m.Document)
+	})
 }
 
 // GetDocument retrieves a document.
@@ -65,22 +79,90 @@ func (s *Service) ListDocuments(ctx context.Context) ([]Document, error) {
 	return s.repo.List(ctx)
 }
 
+// QueryDocuments retrieves the documents matching q.
+func (s *Service) QueryDocuments(ctx context.Context, q Query) ([]Document, error) {
+	docs, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return q.Apply(docs), nil
+}
+
 // DeleteDocument removes a document.
 func (s *Service) DeleteDocument(ctx context.Context, id string) error {
 	if id == "" {
 		return errors.New("document ID cannot be empty")
 	}
-	return s.repo.Delete(ctx, id)
+	return s.apply(ctx, &Mutation{Kind: MutationDelete, Document: Document{ID: id}}, func(ctx context.Context, m *Mutation) error {
+		return s.repo.Delete(ctx, m.Document.ID)
+	})
+}
+
+// activeTransactionKey is the context key carrying the transactions of enclosing WithTransaction calls.
+const activeTransactionKey contextKey = "active_transaction"
+
+// activeTransaction is a transaction carried by a context, with the repository it
+// belongs to. Contexts may carry one per repository (e.g. inside WithMultiTransaction).
+type activeTransaction struct {
+	repo   Repository
+	tx     Transaction
+	parent *activeTransaction
+}
+
+// ContextWithTransaction returns a context carrying tx, a transaction of this
+// service's repository. WithTransaction calls made with it, on any Service over
+// the same repository, run inside tx as savepoints instead of starting a new
+// transaction. Services over other repositories are not affected.
+func (s *Service) ContextWithTransaction(ctx context.Context, tx Transaction) context.Context {
+	parent, _ := ctx.Value(activeTransactionKey).(*activeTransaction)
+	return context.WithValue(ctx, activeTransactionKey, &activeTransaction{repo: s.repo, tx: tx, parent: parent})
 }
 
+// TransactionFromContext returns the transaction of this service's repository
+// carried by ctx, if any.
+func (s *Service) TransactionFromContext(ctx context.Context) (Transaction, bool) {
+	for a, _ := ctx.Value(activeTransactionKey).(*activeTransaction); a != nil; a = a.parent {
+		if sameRepository(a.repo, s.repo) {
+			return a.tx, true
+		}
+	}
+	return nil, false
+}
+
+// sameRepository reports whether a and b are the same repository, without
+// panicking on implementations that are not comparable.
+func sameRepository(a, b Repository) bool {
+	if a == nil || b == nil || reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// savepointSeq generates unique names for the savepoints of nested transactions.
+var savepointSeq atomic.Int64
+
 // WithTransaction executes a function within a transaction.
+//
+// If ctx carries a transaction of the same repository (see ContextWithTransaction),
+// the call is nested:
+// fn runs inside that transaction after a savepoint, and an error from fn rolls
+// back only the changes made since the savepoint. The outer transaction commits.
 func (s *Service) WithTransaction(ctx context.Context, fn func(tx Transaction) error) error {
-	tr, ok := s.repo.(Transactional)
-	if !ok {
-		return errors.New("repository does not support transactions")
+	if outer, ok := s.TransactionFromContext(ctx); ok {
+		name := fmt.Sprintf("nested-%d", savepointSeq.Add(1))
+		if err := outer.Savepoint(ctx, name); err != nil {
+			return err
+		}
+		if err := fn(outer); err != nil {
+			if rbErr := outer.RollbackTo(ctx, name); rbErr != nil {
+				return errors.Join(err, rbErr)
+			}
+			return err
+		}
+		return nil
 	}
 
-	tx, err := tr.Begin(ctx)
+	tx, err := s.Begin(ctx)
 	if err != nil {
 		return err
 	}
@@ -99,34 +181,194 @@ func (s *Service) WithTransaction(ctx context.Context, fn func(tx Transaction) e
 	return tx.Commit(ctx, msg)
 }
 
+// DefaultTransactionAttempts is the number of attempts made by WithRetryingTransaction
+// when none is given.
+const DefaultTransactionAttempts = 5
+
+// WithRetryingTransaction executes a function within a transaction, re-running it
+// from scratch when the commit fails with ErrConflict. fn must be safe to repeat.
+// maxAttempts <= 0 means DefaultTransactionAttempts.
+func (s *Service) WithRetryingTransaction(ctx context.Context, maxAttempts int, fn func(tx Transaction) error) error {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultTransactionAttempts
+	}
+
+	backoff := 10 * time.Millisecond
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = s.WithTransaction(ctx, fn)
+		if !errors.Is(err, ErrConflict) || attempt == maxAttempts {
+			return err
+		}
+
+		// Jittered backoff, so competing writers do not retry in lockstep.
+		timer := time.NewTimer(backoff + time.Duration(rand.Int64N(int64(backoff))))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+	return err
+}
+
 // Begin initiates a transaction manually.
-// Exposed for power users or custom workflows.
+// Exposed for power users or custom workflows. The service's hooks (see WithHook)
+// run around the transaction's saves, deletes and commit.
 func (s *Service) Begin(ctx context.Context) (Transaction, error) {
 	tr, ok := s.repo.(Transactional)
 	if !ok {
 		return nil, errors.New("repository does not support transactions")
 	}
-	return tr.Begin(ctx)
+	tx, err := tr.Begin(ctx)
+	if err != nil || len(s.hooks) == 0 {
+		return tx, err
+	}
+	return &hookedTransaction{Transaction: tx, s: s}, nil
+}
+
+// History returns the revisions of a document, newest first.
+func (s *Service) History(ctx context.Context, id string) ([]Revision, error) {
+	if id == "" {
+		return nil, errors.New("document ID cannot be empty")
+	}
+	v, ok := s.repo.(Versioned)
+	if !ok {
+		return nil, errors.New("repository does not support history")
+	}
+	return v.History(ctx, id)
+}
+
+// Workspace returns a new Service bound to the given workspace of the repository.
+// Writes through the returned Service are isolated until the workspace is merged.
+func (s *Service) Workspace(ctx context.Context, name string) (*Service, error) {
+	b, ok := s.repo.(Branchable)
+	if !ok {
+		return nil, errors.New("repository does not support workspaces")
+	}
+	repo, err := b.Workspace(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return NewService(repo, WithEventBuffer(s.eventBufferSize), WithOverflowPolicy(s.overflowPolicy), WithHook(s.hooks...)), nil
 }
 
 // Watch observes changes in the repository if supported.
+// It is a Subscribe with the service's buffer size and overflow policy.
 func (s *Service) Watch(ctx context.Context, pattern string) (<-chan Event, error) {
+	sub, err := s.Subscribe(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return sub.Events(), nil
+}
+
+// Subscribe registers a subscriber for the changes matching pattern (a glob on
+// Event.Path, or on the ID if the adapter reports no path). All subscribers share
+// a single adapter watch; each one has its own buffer, handled by its
+// OverflowPolicy when full. The subscription ends when ctx is done.
+func (s *Service) Subscribe(ctx context.Context, pattern string, opts ...SubscribeOption) (*Subscription, error) {
 	w, ok := s.repo.(Watchable)
 	if !ok {
 		return nil, errors.New("repository does not support watching")
 	}
 
-	// The repository adapter is now responsible for handling debouncing, routing,
-	// and event isolation using the lifecycle Control Plane.
-	return w.Watch(ctx, pattern)
+	s.mu.Lock()
+	if s.broker == nil {
+		// The repository adapter handles debouncing and event isolation; the broker
+		// only fans its events out.
+		s.broker = newBroker(w)
+	}
+	b := s.broker
+	sub := &Subscription{
+		pattern: pattern,
+		buffer:  s.eventBufferSize,
+		policy:  s.overflowPolicy,
+		done:    make(chan struct{}),
+	}
+	s.mu.Unlock()
+
+	for _, opt := range opts {
+		opt(sub)
+	}
+	if sub.buffer <= 0 {
+		sub.buffer = DefaultEventBuffer
+	}
+	if sub.policy == "" {
+		sub.policy = OverflowBlock
+	}
+	sub.events = make(chan Event, sub.buffer)
+
+	if err := b.subscribe(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Changes returns the logged changes after the cursor, oldest first.
+func (s *Service) Changes(ctx context.Context, since Cursor) ([]Event, error) {
+	l, ok := s.repo.(ChangeLog)
+	if !ok {
+		return nil, errors.New("repository does not support change logs")
+	}
+	return l.Changes(ctx, since)
+}
+
+// WatchFrom replays the changes logged after the cursor, then goes live.
+// Consumers that persist the Cursor of the last processed event can resume
+// after a restart without missing changes.
+func (s *Service) WatchFrom(ctx context.Context, since Cursor, pattern string) (<-chan Event, error) {
+	l, ok := s.repo.(ChangeLog)
+	if !ok {
+		return nil, errors.New("repository does not support change logs")
+	}
+	return l.WatchFrom(ctx, since, pattern)
 }
 
 // Reconcile synchronizes internal state (cache) with valid storage.
 // Returns a list of events representing detected changes (offline edits).
 // If the repository does not support reconciliation, returns nil, nil.
+//
+// Hooks see the detected changes after the fact, as a MutationReconcile; an error
+// they return (e.g. an invalid document edited offline) is returned with the events.
 func (s *Service) Reconcile(ctx context.Context) ([]Event, error) {
-	if r, ok := s.repo.(Reconcilable); ok {
+	r, ok := s.repo.(Reconcilable)
+	if !ok {
+		return nil, nil
+	}
+	if len(s.hooks) == 0 {
 		return r.Reconcile(ctx)
 	}
-	return nil, nil
+
+	var events []Event
+	err := s.apply(ctx, &Mutation{Kind: MutationReconcile}, func(ctx context.Context, m *Mutation) error {
+		var err error
+		if events, err = r.Reconcile(ctx); err != nil {
+			return err
+		}
+		m.Changes = s.reconciledChanges(ctx, events)
+		return nil
+	})
+	return events, err
+}
+
+// reconciledChanges converts reconciled events into the mutations they stand for.
+func (s *Service) reconciledChanges(ctx context.Context, events []Event) []Mutation {
+	changes := make([]Mutation, 0, len(events))
+	for _, e := range events {
+		if e.Type == EventDelete {
+			changes = append(changes, Mutation{Kind: MutationDelete, Document: Document{ID: e.ID}})
+			continue
+		}
+		doc := Document{ID: e.ID}
+		if e.Document != nil {
+			doc = *e.Document
+		} else if got, err := s.repo.Get(ctx, e.ID); err == nil {
+			doc = got
+		}
+		changes = append(changes, Mutation{Kind: MutationSave, Document: doc})
+	}
+	return changes
 }
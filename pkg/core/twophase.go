@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Coordination identifies a distributed (two-phase) transaction for recovery.
+type Coordination struct {
+	ID string // Global transaction ID, shared by all participants.
+	// Decision is where the coordinating participant records the commit decision
+	// (see DecisionLog). A participant that restarts while prepared commits if the
+	// decision exists and rolls back otherwise (presumed abort).
+	Decision string
+}
+
+// Preparable is implemented by transactions that can take part in a two-phase commit.
+type Preparable interface {
+	// Prepare validates the transaction and durably records it without applying it.
+	// The transaction keeps its locks until Commit or Rollback. After a successful
+	// Prepare, Commit does not fail on validation; if it fails otherwise, the
+	// transaction is completed on the next Initialize.
+	Prepare(ctx context.Context, msg string, coord Coordination) error
+}
+
+// DecisionLog is implemented by transactions whose repository can durably record
+// the outcome of a two-phase commit. The first participant of WithMultiTransaction
+// must implement it.
+type DecisionLog interface {
+	// DecisionLocation returns where the decision for the given transaction ID is recorded.
+	DecisionLocation(id string) string
+	// RecordDecision durably records the commit decision. This is the commit point.
+	RecordDecision(ctx context.Context, id string) error
+	// ClearDecision removes the decision once every participant has committed.
+	ClearDecision(ctx context.Context, id string) error
+}
+
+// WithMultiTransaction executes fn with one transaction per service and commits
+// them atomically using two-phase commit: every transaction is prepared (validated
+// and journaled), the decision is recorded by the first participant, then all are
+// committed. If fn or any Prepare fails, every transaction is rolled back.
+//
+// Services must be backed by distinct repositories. fn receives the transactions
+// in the order of services.
+func WithMultiTransaction(ctx context.Context, services []*Service, fn func(txs []Transaction) error) error {
+	if len(services) == 0 {
+		return errors.New("no services given")
+	}
+
+	txs := make([]Transaction, 0, len(services))
+	rollbackAll := func() {
+		for _, tx := range txs {
+			tx.Rollback(ctx)
+		}
+	}
+
+	for _, svc := range services {
+		tx, err := svc.Begin(ctx)
+		if err != nil {
+			rollbackAll()
+			return err
+		}
+		txs = append(txs, tx)
+		if _, ok := unhooked(tx).(Preparable); !ok {
+			rollbackAll()
+			return errors.New("repository does not support two-phase commit")
+		}
+	}
+	log, ok := unhooked(txs[0]).(DecisionLog)
+	if !ok {
+		rollbackAll()
+		return errors.New("first repository cannot record two-phase commit decisions")
+	}
+
+	if err := fn(txs); err != nil {
+		rollbackAll()
+		return err
+	}
+
+	msg := "batch transaction"
+	if val, ok := ctx.Value(ChangeReasonKey).(string); ok && val != "" {
+		msg = val
+	}
+
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		rollbackAll()
+		return err
+	}
+	coord := Coordination{ID: hex.EncodeToString(id[:])}
+	coord.Decision = log.DecisionLocation(coord.ID)
+
+	// Phase 1: prepare
+	for i, tx := range txs {
+		if err := tx.(Preparable).Prepare(ctx, msg, coord); err != nil {
+			rollbackAll()
+			return fmt.Errorf("prepare failed on participant %d: %w", i, err)
+		}
+	}
+	if err := log.RecordDecision(ctx, coord.ID); err != nil {
+		rollbackAll()
+		return fmt.Errorf("failed to record commit decision: %w", err)
+	}
+
+	// Phase 2: commit. The decision is final; failures are completed by recovery.
+	var errs []error
+	for i, tx := range txs {
+		if err := tx.Commit(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("commit failed on participant %d (will complete on next start): %w", i, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	// Everything is committed; a decision left behind by a failure here is harmless.
+	_ = log.ClearDecision(ctx, coord.ID)
+	return nil
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+// QueryChange describes how a change affects the result set of a live query.
+type QueryChange string
+
+const (
+	QueryEnter  QueryChange = "ENTER"  // The document started matching the query.
+	QueryLeave  QueryChange = "LEAVE"  // The document stopped matching (or was deleted).
+	QueryUpdate QueryChange = "UPDATE" // The document changed and still matches.
+)
+
+// QueryEvent is a change to the result set of a live query (see Service.WatchQuery).
+// The embedded Event always carries Document (the new version, or the last known one
+// if the document was deleted) and, for documents already in the result set, Previous.
+type QueryEvent struct {
+	Event
+	Change QueryChange
+}
+
+func (e QueryEvent) String() string {
+	return fmt.Sprintf("%s %s", e.Change, e.ID)
+}
+
+// WatchQuery keeps a live result set of the documents matching q and reports
+// every document entering, leaving or changing within it. The documents matching
+// when the watch starts are reported first, as QueryEnter events without a Type.
+//
+// Conditions are evaluated against each new version of a document and compared
+// with its membership before the change, so "status changed to published" is
+// the QueryEnter of a query on status = published. OrderBy and Limit are ignored.
+func (s *Service) WatchQuery(ctx context.Context, q Query) (<-chan QueryEvent, error) {
+	live := Query{Pattern: q.Pattern, Where: q.Where}
+
+	// Watch before listing, so changes made in between are not missed.
+	events, err := s.Watch(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	docs, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string]Document)
+	var initial []QueryEvent
+	for _, doc := range live.Apply(docs) {
+		members[doc.ID] = doc
+		initial = append(initial, QueryEvent{Event: Event{ID: doc.ID, Document: &doc}, Change: QueryEnter})
+	}
+
+	out := make(chan QueryEvent, s.eventBufferSize)
+	go func() {
+		defer close(out)
+		send := func(e QueryEvent) bool {
+			select {
+			case out <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for _, e := range initial {
+			if !send(e) {
+				return
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				qe, ok := s.evaluate(ctx, live, members, e)
+				if ok && !send(qe) {
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// evaluate updates the result set with a change and returns its effect, if any.
+func (s *Service) evaluate(ctx context.Context, q Query, members map[string]Document, e Event) (QueryEvent, bool) {
+	old, was := members[e.ID]
+
+	var doc *Document
+	if e.Type != EventDelete {
+		doc = e.Document
+		if doc == nil {
+			d, err := s.repo.Get(ctx, e.ID)
+			if err != nil {
+				// Gone since the event was emitted; a delete event follows.
+				return QueryEvent{}, false
+			}
+			doc = &d
+		}
+	}
+	matches := doc != nil && q.Match(*doc)
+
+	qe := QueryEvent{Event: e}
+	if was {
+		qe.Previous = &old
+	}
+	switch {
+	case matches && !was:
+		qe.Change = QueryEnter
+	case matches && was:
+		qe.Change = QueryUpdate
+	case !matches && was:
+		qe.Change = QueryLeave
+	default:
+		return QueryEvent{}, false
+	}
+
+	if matches {
+		members[e.ID] = *doc
+	} else {
+		delete(members, e.ID)
+	}
+	qe.Document = doc
+	if doc == nil {
+		qe.Document = &old
+	}
+	return qe, true
+}
@@ -0,0 +1,98 @@
+package core_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// watchableRepository is a MockRepository whose changes are fed through a channel.
+type watchableRepository struct {
+	*MockRepository
+	events chan core.Event
+	mu     sync.Mutex
+}
+
+func (w *watchableRepository) Get(ctx context.Context, id string) (core.Document, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.MockRepository.Get(ctx, id)
+}
+
+func (w *watchableRepository) Watch(ctx context.Context, pattern string) (<-chan core.Event, error) {
+	return w.events, nil
+}
+
+// change saves or deletes a document and emits the matching event.
+func (w *watchableRepository) change(typ core.EventType, doc core.Document) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if typ == core.EventDelete {
+		delete(w.docs, doc.ID)
+	} else {
+		w.docs[doc.ID] = doc
+	}
+	w.events <- core.Event{Type: typ, ID: doc.ID}
+}
+
+func TestService_WatchQuery(t *testing.T) {
+	repo := &watchableRepository{MockRepository: NewMockRepository(), events: make(chan core.Event, 10)}
+	repo.docs["posts/a"] = core.Document{ID: "posts/a", Metadata: core.Metadata{"status": "published"}}
+	repo.docs["posts/b"] = core.Document{ID: "posts/b", Metadata: core.Metadata{"status": "draft"}}
+	service := core.NewService(repo)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results, err := service.WatchQuery(ctx, core.Query{
+		Pattern: "posts/*",
+		Where:   []core.Condition{{Field: "status", Op: core.OpEq, Value: "published"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func() core.QueryEvent {
+		t.Helper()
+		select {
+		case e := <-results:
+			return e
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for query event")
+		}
+		return core.QueryEvent{}
+	}
+	expect := func(change core.QueryChange, id string) core.QueryEvent {
+		t.Helper()
+		e := next()
+		if e.Change != change || e.ID != id {
+			t.Fatalf("expected %s %s, got %s", change, id, e)
+		}
+		return e
+	}
+
+	expect(core.QueryEnter, "posts/a")
+
+	// Draft published: enters the result set.
+	repo.change(core.EventModify, core.Document{ID: "posts/b", Metadata: core.Metadata{"status": "published"}})
+	expect(core.QueryEnter, "posts/b")
+
+	repo.change(core.EventModify, core.Document{ID: "posts/b", Metadata: core.Metadata{"status": "published", "title": "B"}})
+	e := expect(core.QueryUpdate, "posts/b")
+	if e.Previous == nil || e.Previous.Metadata["title"] != nil || e.Document.Metadata["title"] != "B" {
+		t.Errorf("expected previous and new versions, got %+v / %+v", e.Previous, e.Document)
+	}
+
+	// Non-matching changes are not reported.
+	repo.change(core.EventCreate, core.Document{ID: "pages/about", Metadata: core.Metadata{"status": "published"}})
+	repo.change(core.EventModify, core.Document{ID: "posts/a", Metadata: core.Metadata{"status": "archived"}})
+	expect(core.QueryLeave, "posts/a")
+
+	repo.change(core.EventDelete, core.Document{ID: "posts/b"})
+	e = expect(core.QueryLeave, "posts/b")
+	if e.Document == nil || e.Document.Metadata["title"] != "B" {
+		t.Errorf("expected last known version, got %+v", e.Document)
+	}
+}
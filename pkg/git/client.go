@@ -1,24 +1,52 @@
 package git
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // Client wraps git command execution with a global file-based lock for process safety.
 type Client struct {
-	WorkDir  string
-	Logger   *slog.Logger
-	lockPath string
+	WorkDir   string
+	Logger    *slog.Logger
+	Signing   *Signing      // Optional. When set, commits are signed and signatures verified with it.
+	LockLease time.Duration // Lease of the process lock (renewed while held). Zero means DefaultLockLease.
+	lockPath  string
 }
 
-// DefaultLockTimeout is the maximum time to wait for a lock before giving up.
-const DefaultLockTimeout = 30 * time.Second
+// Signing configures commit signatures.
+type Signing struct {
+	Format         string // "openpgp" (default), "ssh" or "x509".
+	Key            string // GPG key ID, or path to the SSH key.
+	AllowedSigners string // SSH allowed signers file, used to verify signatures.
+}
+
+// configArgs returns the "-c key=value" arguments derived from the signing configuration.
+func (s *Signing) configArgs() []string {
+	if s == nil {
+		return nil
+	}
+	var args []string
+	if s.Format != "" {
+		args = append(args, "-c", "gpg.format="+s.Format)
+	}
+	if s.Key != "" {
+		args = append(args, "-c", "user.signingkey="+s.Key)
+	}
+	if s.AllowedSigners != "" {
+		args = append(args, "-c", "gpg.ssh.allowedSignersFile="+s.AllowedSigners)
+	}
+	return args
+}
 
 // NewClient creates a new git client for the given working directory.
 func NewClient(workDir string, lockFile string, logger *slog.Logger) *Client {
@@ -29,36 +57,6 @@ func NewClient(workDir string, lockFile string, logger *slog.Logger) *Client {
 	}
 }
 
-// Lock acquires a file-based lock. It blocks until the lock is acquired.
-func (c *Client) Lock() (func(), error) {
-	fullLockPath := filepath.Join(c.WorkDir, c.lockPath)
-	start := time.Now()
-
-	for {
-		// Try to create lock file atomically
-		f, err := os.OpenFile(fullLockPath, os.O_CREATE|os.O_EXCL, 0666)
-		if err == nil {
-			f.Close()
-			// Return unlock function
-			return func() {
-				os.Remove(fullLockPath)
-			}, nil
-		}
-
-		if os.IsExist(err) || os.IsPermission(err) {
-			// Lock exists (or access denied on Windows), wait and retry
-			// Simple spinlock with backoff.
-			if time.Since(start) > DefaultLockTimeout {
-				return nil, fmt.Errorf("timeout waiting for lock %s after %v", fullLockPath, DefaultLockTimeout)
-			}
-			time.Sleep(10 * time.Millisecond)
-			continue
-		}
-
-		return nil, fmt.Errorf("failed to acquire lock: %w", err)
-	}
-}
-
 // Run executes a raw git command in the working directory.
 // NOTE: It does NOT acquire the lock automatically. The caller must manage transaction safety via Client.Lock().
 func (c *Client) Run(args ...string) (string, error) {
@@ -66,7 +64,7 @@ func (c *Client) Run(args ...string) (string, error) {
 		c.Logger.Debug("executing git", "args", args, "dir", c.WorkDir)
 	}
 
-	cmd := exec.Command("git", args...)
+	cmd := exec.Command("git", append(c.Signing.configArgs(), args...)...)
 	cmd.Dir = c.WorkDir
 
 	out, err := cmd.CombinedOutput()
@@ -106,17 +104,217 @@ func (c *Client) Rm(files ...string) error {
 	return err
 }
 
+// CommitOptions customizes the identity and trailers of a commit.
+type CommitOptions struct {
+	Author   string   // "Name <email>". Empty means the configured git identity.
+	Trailers []string // "Key: value" lines appended as git trailers.
+}
+
 // Commit records changes to the repository.
 func (c *Client) Commit(msg string) error {
-	_, err := c.Run("commit", "-m", msg)
+	return c.CommitWith(msg, CommitOptions{})
+}
+
+// CommitWith records changes to the repository using the given options.
+func (c *Client) CommitWith(msg string, opts CommitOptions) error {
+	args := []string{"commit", "-m", msg}
+	if opts.Author != "" {
+		args = append(args, "--author", opts.Author)
+	}
+	if c.Signing != nil && c.Signing.Key != "" {
+		args = append(args, "-S")
+	}
+	for _, t := range opts.Trailers {
+		args = append(args, "--trailer", t)
+	}
+	_, err := c.Run(args...)
 	return err
 }
 
+// LogEntry is a single commit as reported by Log.
+type LogEntry struct {
+	Hash        string
+	Author      string
+	AuthorEmail string
+	Timestamp   int64
+	Subject     string
+	Signature   string // git's %G? code: G, B, U, X, Y, R, E or N.
+	Signer      string
+}
+
+// Log returns the commits touching the given path, newest first, including signature checks.
+func (c *Client) Log(path string) ([]LogEntry, error) {
+	const sep, end = "\x1f", "\x1e"
+	format := strings.Join([]string{"%H", "%an", "%ae", "%at", "%s", "%G?", "%GS"}, "%x1f") + "%x1e"
+	// Signature checks print to stderr (e.g. a missing gpg.ssh.allowedSignersFile),
+	// so only stdout is parsed.
+	out, err := c.output(nil, "log", "--follow", "--format="+format, "--", path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []LogEntry
+	for _, record := range strings.Split(out, end) {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+		fields := strings.Split(record, sep)
+		if len(fields) < 7 {
+			continue
+		}
+		ts, _ := strconv.ParseInt(fields[3], 10, 64)
+		entries = append(entries, LogEntry{
+			Hash:        fields[0],
+			Author:      fields[1],
+			AuthorEmail: fields[2],
+			Timestamp:   ts,
+			Subject:     fields[4],
+			Signature:   fields[5],
+			Signer:      fields[6],
+		})
+	}
+	if err := c.markUnverifiable(entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// markUnverifiable reports as "E" (cannot be checked) the entries git reports as
+// unsigned ("N") whose commit does carry a signature: git answers N when it has no
+// way to verify one, e.g. without gpg.ssh.allowedSignersFile.
+func (c *Client) markUnverifiable(entries []LogEntry) error {
+	var hashes []string
+	for _, e := range entries {
+		if e.Signature == "N" {
+			hashes = append(hashes, e.Hash)
+		}
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	out, err := c.output(strings.NewReader(strings.Join(hashes, "\n")+"\n"), "cat-file", "--batch")
+	if err != nil {
+		return err
+	}
+	signed := make(map[string]bool)
+	for out != "" {
+		header, rest, _ := strings.Cut(out, "\n")
+		parts := strings.Fields(header)
+		if len(parts) != 3 {
+			break // "<hash> missing"
+		}
+		size, err := strconv.Atoi(parts[2])
+		if err != nil || size > len(rest) {
+			return fmt.Errorf("unexpected git cat-file output: %q", header)
+		}
+		headers, _, _ := strings.Cut(rest[:size], "\n\n")
+		signed[parts[0]] = strings.Contains(headers, "\ngpgsig ") || strings.Contains(headers, "\ngpgsig-sha256 ")
+		out = strings.TrimPrefix(rest[size:], "\n")
+	}
+	for i := range entries {
+		if entries[i].Signature == "N" && signed[entries[i].Hash] {
+			entries[i].Signature = "E"
+		}
+	}
+	return nil
+}
+
+// output executes a git command with the given stdin and returns its stdout, untrimmed.
+// Unlike Run, stderr is kept out of the output: it goes to the error, or to the
+// logger when the command succeeds.
+func (c *Client) output(stdin io.Reader, args ...string) (string, error) {
+	if c.Logger != nil {
+		c.Logger.Debug("executing git", "args", args, "dir", c.WorkDir)
+	}
+
+	cmd := exec.Command("git", append(c.Signing.configArgs(), args...)...)
+	cmd.Dir = c.WorkDir
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w\nOutput: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	if stderr.Len() > 0 && c.Logger != nil {
+		c.Logger.Debug("git stderr", "args", args, "stderr", strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// Head returns the commit HEAD points to, or "" if the repository has no commits yet.
+func (c *Client) Head() (string, error) {
+	out, err := c.output(nil, "rev-parse", "-q", "--verify", "HEAD^{commit}")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// FirstChild returns the commit following base on the first-parent history of HEAD,
+// or "" if base is HEAD or not one of its ancestors. An empty base stands for the
+// root of the history.
+func (c *Client) FirstChild(base string) (string, error) {
+	args := []string{"rev-list", "--first-parent", "--reverse", "HEAD"}
+	if base != "" {
+		if _, err := c.output(nil, "merge-base", "--is-ancestor", base, "HEAD"); err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+				return "", nil
+			}
+			return "", err
+		}
+		args = append(args, "^"+base)
+	}
+	out, err := c.output(nil, args...)
+	if err != nil {
+		return "", err
+	}
+	first, _, _ := strings.Cut(out, "\n")
+	return strings.TrimSpace(first), nil
+}
+
 // Status returns the porcelain status of the repo.
 func (c *Client) Status() (string, error) {
 	return c.Run("status", "--porcelain")
 }
 
+// ChangedPaths returns the given paths (all of them, if none) that are untracked or
+// differ from HEAD, as reported by git status. Paths are slash-separated and unquoted.
+func (c *Client) ChangedPaths(paths ...string) ([]string, error) {
+	// No optional locks: callers may run concurrently with commits.
+	args := append([]string{"--no-optional-locks", "status", "--porcelain", "-z", "--untracked-files=all", "--"}, paths...)
+	out, err := c.output(nil, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		changed = append(changed, entry[3:])
+		if entry[0] == 'R' || entry[0] == 'C' {
+			// Renames and copies are followed by their source path.
+			i++
+			if i < len(entries) && entries[i] != "" {
+				changed = append(changed, entries[i])
+			}
+		}
+	}
+	return changed, nil
+}
+
 // HasRemote checks if there is a 'origin' remote configured.
 // For now, we hardcode 'origin' as the default remote to check.
 func (c *Client) HasRemote() bool {
@@ -170,12 +368,84 @@ func (c *Client) Clean(files ...string) error {
 	return err
 }
 
+// FileChange describes a single path changed between two revisions.
+type FileChange struct {
+	Status string // Single-letter git status (A, M, D)
+	Path   string // Path relative to the repository root (forward slashes)
+}
+
+// AddWorktree creates a new branch and checks it out into a separate working tree at path.
+func (c *Client) AddWorktree(path, branch string) error {
+	_, err := c.Run("worktree", "add", "-b", branch, path)
+	return err
+}
+
+// RemoveWorktree removes a working tree created by AddWorktree, discarding local modifications.
+func (c *Client) RemoveWorktree(path string) error {
+	_, err := c.Run("worktree", "remove", "--force", path)
+	return err
+}
+
+// DeleteBranch forcefully deletes a local branch.
+func (c *Client) DeleteBranch(branch string) error {
+	_, err := c.Run("branch", "-D", branch)
+	return err
+}
+
+// Branches returns the local branches matching the given pattern (e.g. "loam/*").
+func (c *Client) Branches(pattern string) ([]string, error) {
+	out, err := c.Run("branch", "--list", "--format=%(refname:short)", pattern)
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// ChangedFiles lists the files changed on 'branch' since it diverged from 'base'.
+func (c *Client) ChangedFiles(base, branch string) ([]FileChange, error) {
+	out, err := c.Run("diff", "--name-status", "--no-renames", base+"..."+branch)
+	if err != nil {
+		return nil, err
+	}
+	var changes []FileChange
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		changes = append(changes, FileChange{Status: parts[0][:1], Path: parts[1]})
+	}
+	return changes, nil
+}
+
+// Merge merges the given branch into the current one, always creating a merge commit.
+// If the merge fails (e.g. conflicts), it is aborted so the working tree is left untouched.
+func (c *Client) Merge(branch, msg string) error {
+	return c.MergeWith(branch, msg, CommitOptions{})
+}
+
+// MergeWith merges like Merge, applying the commit options to the merge commit.
+func (c *Client) MergeWith(branch, msg string, opts CommitOptions) error {
+	if _, err := c.Run("merge", "--no-ff", "--no-commit", branch); err != nil {
+		_, _ = c.Run("merge", "--abort")
+		return err
+	}
+	if err := c.CommitWith(msg, opts); err != nil {
+		_, _ = c.Run("merge", "--abort")
+		return err
+	}
+	return nil
+}
+
 // IsRepo checks if the current working directory is a valid git repository.
 func (c *Client) IsRepo() bool {
-	// Check for .git directory
+	// Check for .git directory (or a .git file, as used by linked worktrees)
 	gitDir := filepath.Join(c.WorkDir, ".git")
-	info, err := os.Stat(gitDir)
-	return err == nil && info.IsDir()
+	_, err := os.Stat(gitDir)
+	return err == nil
 }
 
 // IsInstalled checks if git is available in the system PATH.
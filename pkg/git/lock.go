@@ -0,0 +1,286 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultLockTimeout is the maximum time to wait for a lock before giving up.
+const DefaultLockTimeout = 30 * time.Second
+
+// DefaultLockLease is how long a lock stays valid without renewal.
+// Holders renew the lease while they run, so only crashed holders let it expire.
+const DefaultLockLease = 30 * time.Second
+
+// ErrLockHeld is returned by BreakLock when the lock belongs to a live holder.
+var ErrLockHeld = errors.New("lock is held by a live process")
+
+// LockInfo is the content of a lock file: who holds the lock and until when.
+type LockInfo struct {
+	PID        int       `json:"pid"`
+	Hostname   string    `json:"hostname"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// LockStatus describes the current state of a held lock.
+type LockStatus struct {
+	LockInfo
+	Stale  bool   `json:"stale"`
+	Reason string `json:"reason,omitempty"` // Why the lock is considered stale.
+}
+
+func (c *Client) fullLockPath() string {
+	return filepath.Join(c.WorkDir, c.lockPath)
+}
+
+func (c *Client) lease() time.Duration {
+	if c.LockLease > 0 {
+		return c.LockLease
+	}
+	return DefaultLockLease
+}
+
+// Lock acquires a file-based lock. It blocks until the lock is acquired or DefaultLockTimeout elapses.
+func (c *Client) Lock() (func(), error) {
+	return c.LockContext(context.Background())
+}
+
+// LockContext acquires a file-based lock, waiting until it is free, ctx is done, or DefaultLockTimeout elapses.
+//
+// The lock file records the holder (PID, hostname) and a lease that is renewed in the
+// background while held. Locks whose holder is dead (same host) or whose lease expired
+// are broken automatically.
+func (c *Client) LockContext(ctx context.Context) (func(), error) {
+	fullLockPath := c.fullLockPath()
+
+	deadline := time.NewTimer(DefaultLockTimeout)
+	defer deadline.Stop()
+
+	backoff := 5 * time.Millisecond
+	for {
+		info, err := c.tryLock()
+		if err == nil {
+			return c.holdLock(info), nil
+		}
+
+		if !os.IsExist(err) && !os.IsPermission(err) {
+			return nil, fmt.Errorf("failed to acquire lock: %w", err)
+		}
+
+		// Lock exists (or access denied on Windows). Break it if its holder is gone.
+		if status, serr := c.LockStatus(); serr == nil && status != nil && status.Stale {
+			if c.Logger != nil {
+				c.Logger.Warn("breaking stale lock", "path", fullLockPath, "pid", status.PID, "host", status.Hostname, "reason", status.Reason)
+			}
+			if c.breakStale(status) {
+				continue
+			}
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("waiting for lock %s: %w", fullLockPath, ctx.Err())
+		case <-deadline.C:
+			timer.Stop()
+			return nil, fmt.Errorf("timeout waiting for lock %s after %v", fullLockPath, DefaultLockTimeout)
+		case <-timer.C:
+		}
+		if backoff < 100*time.Millisecond {
+			backoff *= 2
+		}
+	}
+}
+
+// tryLock attempts to create the lock file atomically and record the holder.
+func (c *Client) tryLock() (LockInfo, error) {
+	f, err := os.OpenFile(c.fullLockPath(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return LockInfo{}, err
+	}
+	defer f.Close()
+
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	info := LockInfo{
+		PID:        os.Getpid(),
+		Hostname:   hostname,
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(c.lease()),
+	}
+	data, _ := json.Marshal(info)
+	if _, err := f.Write(data); err != nil {
+		// The file exists now; an unreadable holder would only be broken by lease expiry.
+		os.Remove(c.fullLockPath())
+		return LockInfo{}, fmt.Errorf("failed to write lock: %w", err)
+	}
+	return info, nil
+}
+
+// holdLock renews the lease in the background and returns the unlock function.
+func (c *Client) holdLock(info LockInfo) func() {
+	fullLockPath := c.fullLockPath()
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(c.lease() / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				// Never renew a lock that was broken (and maybe taken) by someone else.
+				if current, err := readLockInfo(fullLockPath); err != nil || !sameHolder(current, info) {
+					if c.Logger != nil {
+						c.Logger.Warn("lock lost, no longer renewing its lease", "path", fullLockPath)
+					}
+					return
+				}
+				info.ExpiresAt = time.Now().Add(c.lease())
+				if err := writeLockFile(fullLockPath, info); err != nil && c.Logger != nil {
+					c.Logger.Warn("failed to renew lock lease", "path", fullLockPath, "error", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+		// Only remove the lock if it is still ours (it may have been broken by someone else).
+		if current, err := readLockInfo(fullLockPath); err == nil && !sameHolder(current, info) {
+			return
+		}
+		os.Remove(fullLockPath)
+	}
+}
+
+// LockStatus returns the state of the lock, or nil if the lock is free.
+func (c *Client) LockStatus() (*LockStatus, error) {
+	fullLockPath := c.fullLockPath()
+	stat, err := os.Stat(fullLockPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := readLockInfo(fullLockPath)
+	if err != nil {
+		// Legacy (empty) or partially written lock: fall back to the file age.
+		status := &LockStatus{LockInfo: LockInfo{AcquiredAt: stat.ModTime(), ExpiresAt: stat.ModTime().Add(c.lease())}}
+		if time.Now().After(status.ExpiresAt) {
+			status.Stale = true
+			status.Reason = "unreadable lock older than lease"
+		}
+		return status, nil
+	}
+
+	status := &LockStatus{LockInfo: info}
+	hostname, _ := os.Hostname()
+	switch {
+	case info.Hostname == hostname && info.PID > 0 && !processAlive(info.PID):
+		status.Stale = true
+		status.Reason = "holder process is not running"
+	case time.Now().After(info.ExpiresAt):
+		status.Stale = true
+		status.Reason = "lease expired"
+	}
+	return status, nil
+}
+
+// BreakLock removes the lock. Unless force is set, it refuses to break a lock held by a live holder.
+func (c *Client) BreakLock(force bool) error {
+	status, err := c.LockStatus()
+	if err != nil {
+		return err
+	}
+	if status == nil {
+		return nil
+	}
+	if !status.Stale && !force {
+		return fmt.Errorf("%w (pid %d on %s)", ErrLockHeld, status.PID, status.Hostname)
+	}
+	if err := os.Remove(c.fullLockPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// breakStale removes a stale lock, making sure it is still the one judged stale.
+// The lock is first moved aside, so a waiter racing to break the same lock
+// cannot delete a fresh lock acquired in between. Returns true if the lock was broken.
+func (c *Client) breakStale(status *LockStatus) bool {
+	fullLockPath := c.fullLockPath()
+	aside := fmt.Sprintf("%s.stale.%d", fullLockPath, os.Getpid())
+	if err := os.Rename(fullLockPath, aside); err != nil {
+		return false
+	}
+	defer os.Remove(aside)
+
+	if !c.stillStale(aside, status) {
+		// We moved a fresh lock; put it back unless someone acquired a new one meanwhile.
+		_ = os.Link(aside, fullLockPath)
+		return false
+	}
+	return true
+}
+
+// stillStale reports whether the lock moved to path is the one judged stale by status.
+// An unreadable lock may be a fresh one whose holder has not written it yet (between
+// the exclusive create and the write in tryLock), so it only counts as the same lock
+// if it is the old unreadable file LockStatus judged by its age.
+func (c *Client) stillStale(path string, status *LockStatus) bool {
+	moved, err := readLockInfo(path)
+	if err == nil {
+		return sameHolder(moved, status.LockInfo)
+	}
+	stat, err := os.Stat(path)
+	return err == nil && status.PID == 0 && stat.ModTime().Equal(status.AcquiredAt) &&
+		time.Since(stat.ModTime()) > c.lease()
+}
+
+// writeLockFile replaces the lock file through a temporary file and a rename, so
+// that readers never see a partially written holder.
+func writeLockFile(path string, info LockInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.renew.%d", path, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0666); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func readLockInfo(path string) (LockInfo, error) {
+	var info LockInfo
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+func sameHolder(a, b LockInfo) bool {
+	return a.PID == b.PID && a.Hostname == b.Hostname && a.AcquiredAt.Equal(b.AcquiredAt)
+}
@@ -0,0 +1,187 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeLockInfo(t *testing.T, path string, info LockInfo) {
+	t.Helper()
+	data, _ := json.Marshal(info)
+	if err := os.WriteFile(path, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_LockRecordsHolder(t *testing.T) {
+	tmpDir := t.TempDir()
+	client := NewClient(tmpDir, ".loam.lock", nil)
+
+	unlock, err := client.Lock()
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	defer unlock()
+
+	status, err := client.LockStatus()
+	if err != nil || status == nil {
+		t.Fatalf("expected lock status, got %v (err: %v)", status, err)
+	}
+	if status.PID != os.Getpid() || status.Stale {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if err := client.BreakLock(false); !errors.Is(err, ErrLockHeld) {
+		t.Errorf("expected ErrLockHeld for a live lock, got %v", err)
+	}
+}
+
+func TestClient_LockBreaksStale(t *testing.T) {
+	hostname, _ := os.Hostname()
+
+	t.Run("Dead Holder", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		client := NewClient(tmpDir, ".loam.lock", nil)
+		// PIDs are bounded well below this on supported platforms.
+		writeLockInfo(t, filepath.Join(tmpDir, ".loam.lock"), LockInfo{
+			PID:        1 << 30,
+			Hostname:   hostname,
+			AcquiredAt: time.Now(),
+			ExpiresAt:  time.Now().Add(time.Hour),
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		unlock, err := client.LockContext(ctx)
+		if err != nil {
+			t.Fatalf("expected stale lock to be broken, got %v", err)
+		}
+		unlock()
+	})
+
+	t.Run("Expired Lease", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		client := NewClient(tmpDir, ".loam.lock", nil)
+		writeLockInfo(t, filepath.Join(tmpDir, ".loam.lock"), LockInfo{
+			PID:        1,
+			Hostname:   "another-host",
+			AcquiredAt: time.Now().Add(-time.Hour),
+			ExpiresAt:  time.Now().Add(-time.Minute),
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		unlock, err := client.LockContext(ctx)
+		if err != nil {
+			t.Fatalf("expected expired lock to be broken, got %v", err)
+		}
+		unlock()
+	})
+}
+
+func TestClient_LockContextCancel(t *testing.T) {
+	tmpDir := t.TempDir()
+	client := NewClient(tmpDir, ".loam.lock", nil)
+
+	unlock, err := client.Lock()
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.LockContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context deadline error, got %v", err)
+	}
+}
+
+func TestClient_LockRenewsLease(t *testing.T) {
+	tmpDir := t.TempDir()
+	client := NewClient(tmpDir, ".loam.lock", nil)
+	client.LockLease = 150 * time.Millisecond
+
+	unlock, err := client.Lock()
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	defer unlock()
+
+	time.Sleep(400 * time.Millisecond)
+	status, _ := client.LockStatus()
+	if status == nil || status.Stale {
+		t.Errorf("expected lease to be renewed while held, got %+v", status)
+	}
+}
+
+func TestClient_LockStopsRenewingLostLock(t *testing.T) {
+	tmpDir := t.TempDir()
+	lockPath := filepath.Join(tmpDir, ".loam.lock")
+	client := NewClient(tmpDir, ".loam.lock", nil)
+	client.LockLease = 90 * time.Millisecond
+
+	unlock, err := client.Lock()
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+
+	// Another process broke the lease and took the lock.
+	other := LockInfo{PID: 1, Hostname: "another-host", AcquiredAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	writeLockInfo(t, lockPath, other)
+	time.Sleep(200 * time.Millisecond)
+	if current, err := readLockInfo(lockPath); err != nil || !sameHolder(current, other) || !current.ExpiresAt.Equal(other.ExpiresAt) {
+		t.Errorf("renewal overwrote another holder's lock: %+v (err: %v)", current, err)
+	}
+	unlock()
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Errorf("unlock removed another holder's lock: %v", err)
+	}
+
+	// A removed lock is not recreated by the renewal.
+	os.Remove(lockPath)
+	unlock, err = client.Lock()
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	defer unlock()
+	os.Remove(lockPath)
+	time.Sleep(200 * time.Millisecond)
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("renewal recreated a removed lock: %v", err)
+	}
+}
+
+func TestClient_BreakStaleKeepsUnwrittenLock(t *testing.T) {
+	tmpDir := t.TempDir()
+	lockPath := filepath.Join(tmpDir, ".loam.lock")
+	client := NewClient(tmpDir, ".loam.lock", nil)
+
+	// Judged stale as an old holder; replaced by a fresh lock created but not yet written.
+	stale := &LockStatus{LockInfo: LockInfo{PID: 1, Hostname: "another-host", ExpiresAt: time.Now().Add(-time.Minute)}, Stale: true}
+	if err := os.WriteFile(lockPath, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if client.breakStale(stale) {
+		t.Error("broke a fresh, unwritten lock")
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Errorf("fresh lock not put back: %v", err)
+	}
+
+	// An old unreadable lock, judged stale by its age, is still broken.
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+	status, err := client.LockStatus()
+	if err != nil || status == nil || !status.Stale {
+		t.Fatalf("expected old unreadable lock to be stale, got %+v (err: %v)", status, err)
+	}
+	if !client.breakStale(status) {
+		t.Error("old unreadable lock not broken")
+	}
+}
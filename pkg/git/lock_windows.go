@@ -0,0 +1,16 @@
+//go:build windows
+
+package git
+
+import "os"
+
+// processAlive reports whether a process with the given PID exists on this host.
+func processAlive(pid int) bool {
+	// On Windows, FindProcess opens a handle and fails if the process does not exist.
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,246 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect of generated schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// For returns the JSON Schema of the documents typed.Repository[T] stores: the
+// metadata is T encoded as JSON. See Generate.
+func For[T any]() []byte {
+	return Generate(reflect.TypeFor[T]())
+}
+
+// Generate derives a JSON Schema from a Go type, following encoding/json: field names
+// come from json tags, "-" fields are skipped and embedded structs are flattened.
+// Pointers, slices and maps also accept null, as they encode a nil value.
+//
+// Constraints come from the validate tag, a comma-separated list of:
+//
+//	required        the field must be present
+//	oneof=a b c     enum of space-separated values
+//	min=N, max=N    minimum/maximum for numbers, length for strings, items for slices
+//	pattern=RE      regular expression for strings (alias regex=RE); must come last,
+//	                as it may contain commas
+func Generate(t reflect.Type) []byte {
+	g := generator{visiting: make(map[reflect.Type]bool)}
+	root := g.schema(t)
+	root["$schema"] = Draft
+	if t.Name() != "" {
+		root["title"] = t.Name()
+	}
+	data, _ := json.MarshalIndent(root, "", "  ") // Only JSON types are used.
+	return data
+}
+
+var (
+	timeType   = reflect.TypeFor[time.Time]()
+	numberType = reflect.TypeFor[json.Number]()
+)
+
+type generator struct {
+	visiting map[reflect.Type]bool // Structs being generated, to stop on recursive types.
+}
+
+func (g *generator) schema(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case numberType:
+		return map[string]any{"type": "number"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return orNull(g.schema(t.Elem()))
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return orNull(map[string]any{"type": "string", "contentEncoding": "base64"})
+		}
+		return orNull(map[string]any{"type": "array", "items": g.schema(t.Elem())})
+	case reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem()), "minItems": t.Len(), "maxItems": t.Len()}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return orNull(map[string]any{"type": "object"})
+		}
+		return orNull(map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())})
+	case reflect.Struct:
+		return g.object(t)
+	}
+	return map[string]any{} // Interfaces and custom encodings: anything goes.
+}
+
+func (g *generator) object(t reflect.Type) map[string]any {
+	if g.visiting[t] {
+		return map[string]any{"type": "object"}
+	}
+	g.visiting[t] = true
+	defer delete(g.visiting, t)
+
+	props := make(map[string]any)
+	var required []string
+	g.fields(t, props, &required)
+
+	obj := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+	return obj
+}
+
+// fields adds the JSON fields of struct t, including those of embedded structs.
+func (g *generator) fields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, skip := jsonName(f)
+		if skip {
+			continue
+		}
+		if name == "" {
+			// Embedded struct without a json name: its fields are promoted.
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			g.fields(ft, props, required)
+			continue
+		}
+
+		s := g.schema(f.Type)
+		tags := parseTag(f.Tag.Get("validate"))
+		applyTags(s, f.Type, tags)
+		props[name] = s
+		if _, ok := tags["required"]; ok {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// jsonName returns the JSON name of a field, or "" for an embedded struct whose
+// fields are promoted.
+func jsonName(f reflect.StructField) (name string, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	if f.Anonymous && name == "" {
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct {
+			return "", false
+		}
+	}
+	if !f.IsExported() {
+		return "", true
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name, false
+}
+
+// parseTag splits a validate tag into its rules; pattern= (or its alias regex=) takes
+// the rest of the tag.
+func parseTag(tag string) map[string]string {
+	rules := make(map[string]string)
+	for tag != "" {
+		var rule string
+		if strings.HasPrefix(tag, "pattern=") || strings.HasPrefix(tag, "regex=") {
+			rule, tag = tag, ""
+		} else {
+			rule, tag, _ = strings.Cut(tag, ",")
+		}
+		key, value, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if key == "regex" {
+			key = "pattern"
+		}
+		if key != "" {
+			rules[key] = value
+		}
+	}
+	return rules
+}
+
+// applyTags adds the constraints of the validate rules to the schema of a field.
+func applyTags(s map[string]any, t reflect.Type, rules map[string]string) {
+	var enum []any
+	if t.Kind() == reflect.Pointer {
+		enum = append(enum, nil) // A nil pointer encodes as null.
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if values, ok := rules["oneof"]; ok {
+		for _, v := range strings.Fields(values) {
+			enum = append(enum, enumValue(t, v))
+		}
+		s["enum"] = enum
+	}
+	if re, ok := rules["pattern"]; ok {
+		s["pattern"] = re
+	}
+	for bound, keyword := range map[string]string{"min": "minimum", "max": "maximum"} {
+		n, err := strconv.ParseFloat(rules[bound], 64)
+		if err != nil {
+			continue
+		}
+		switch t.Kind() {
+		case reflect.String:
+			s[bound+"Length"] = int(n)
+		case reflect.Slice, reflect.Array:
+			s[bound+"Items"] = int(n)
+		case reflect.Map:
+			s[bound+"Properties"] = int(n)
+		default:
+			s[keyword] = n
+		}
+	}
+}
+
+// enumValue converts a oneof value to the JSON type of the field.
+func enumValue(t reflect.Type, v string) any {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// orNull makes a schema also accept null.
+func orNull(s map[string]any) map[string]any {
+	if typ, ok := s["type"].(string); ok {
+		s["type"] = []string{typ, "null"}
+	}
+	return s
+}
@@ -0,0 +1,101 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aretw0/loam/pkg/core"
+	"github.com/aretw0/loam/pkg/schema"
+)
+
+type Audit struct {
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Service struct {
+	Audit
+	Name     string            `json:"name" validate:"required,pattern=^[a-z]+(-[a-z]+)*$"`
+	Tier     string            `json:"tier,omitempty" validate:"oneof=gold silver"`
+	Replicas int               `json:"replicas" validate:"required,min=1,max=10"`
+	Owner    *string           `json:"owner"`
+	Tags     []string          `json:"tags,omitempty" validate:"max=3"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Internal string            `json:"-"`
+	Parent   *Service          `json:"parent,omitempty"`
+}
+
+func TestGenerate(t *testing.T) {
+	var doc map[string]any
+	if err := json.Unmarshal(schema.For[Service](), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["$schema"] != schema.Draft || doc["title"] != "Service" {
+		t.Errorf("missing dialect or title: %v", doc)
+	}
+	if !reflect.DeepEqual(doc["required"], []any{"name", "replicas"}) {
+		t.Errorf("required = %v", doc["required"])
+	}
+
+	props := doc["properties"].(map[string]any)
+	for _, name := range []string{"Internal", "Audit"} {
+		if _, ok := props[name]; ok {
+			t.Errorf("unexpected property %s", name)
+		}
+	}
+	want := map[string]any{
+		"updated_at": map[string]any{"type": "string", "format": "date-time"},
+		"tier":       map[string]any{"type": "string", "enum": []any{"gold", "silver"}},
+		"replicas":   map[string]any{"type": "integer", "minimum": 1.0, "maximum": 10.0},
+		"owner":      map[string]any{"type": []any{"string", "null"}},
+		"tags":       map[string]any{"type": []any{"array", "null"}, "items": map[string]any{"type": "string"}, "maxItems": 3.0},
+		"parent":     map[string]any{"type": []any{"object", "null"}},
+	}
+	for name, w := range want {
+		if !reflect.DeepEqual(props[name], w) {
+			t.Errorf("%s = %v, want %v", name, props[name], w)
+		}
+	}
+
+	// The generated schema accepts what typed.Repository stores and rejects the rest.
+	v := schema.New()
+	if err := v.Register("services/*", schema.For[Service]()); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(Service{Name: "api-gateway", Tier: "gold", Replicas: 2})
+	var meta core.Metadata
+	json.Unmarshal(data, &meta)
+	if err := v.Validate(core.Document{ID: "services/api", Metadata: meta}); err != nil {
+		t.Errorf("typed document rejected: %v", err)
+	}
+	meta["tier"], meta["name"] = "bronze", "Not Valid"
+	if fields := fieldErrors(t, v.Validate(core.Document{ID: "services/api", Metadata: meta})); len(fields) != 2 {
+		t.Errorf("expected tier and name errors, got %v", fields)
+	}
+}
+
+func TestExport(t *testing.T) {
+	system := t.TempDir()
+	if err := schema.Export(system, "services/*", "service", schema.For[Service]()); err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Export(system, "services/*", "service-v2", schema.For[Service]()); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := schema.LoadConfig(system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Schemas) != 1 || cfg.Schemas[0].Schema != "schemas/service-v2.json" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	v, err := schema.Load(system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Validate(core.Document{ID: "services/db", Metadata: core.Metadata{"replicas": 1}}); err == nil {
+		t.Error("exported schema not applied")
+	}
+}
@@ -0,0 +1,336 @@
+// Package schema validates documents against JSON Schemas mapped to ID globs.
+//
+// Schemas are configured in the vault, in <SystemDir>/schemas.json:
+//
+//	{
+//	  "schemas": [
+//	    {"pattern": "services/*", "schema": "schemas/service.json"},
+//	    {"pattern": "notes/**", "schema": "schemas/note.json", "content": true}
+//	  ]
+//	}
+//
+// Schema paths are relative to the system directory, and schemas may $ref files next
+// to them. A document is validated against every schema whose pattern matches its ID.
+// The validated instance is the document's metadata; with "content", the content is
+// included under the "content" key.
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// ConfigName is the file, inside the system directory, mapping ID globs to schemas.
+const ConfigName = "schemas.json"
+
+// ContentKey is the key holding the document content in the validated instance.
+const ContentKey = "content"
+
+// Rule maps an ID glob to a schema file.
+type Rule struct {
+	Pattern string `json:"pattern"`           // Glob on the document ID.
+	Schema  string `json:"schema"`            // Schema file, relative to the system directory.
+	Content bool   `json:"content,omitempty"` // Also validate the content, under ContentKey.
+}
+
+// Config is the content of the schemas configuration file.
+type Config struct {
+	Schemas []Rule `json:"schemas"`
+}
+
+// Option configures a schema registered with Validator.Register.
+type Option func(*entry)
+
+// WithContent includes the document content, under ContentKey, in the validated instance.
+func WithContent() Option {
+	return func(e *entry) {
+		e.content = true
+	}
+}
+
+type entry struct {
+	pattern string
+	schema  *jsonschema.Schema
+	content bool
+}
+
+// Validator validates documents against the schemas of the patterns matching their IDs.
+// It is not safe to Register schemas while validating.
+type Validator struct {
+	entries []entry
+	printer *message.Printer
+}
+
+// New returns a validator with no schemas.
+func New() *Validator {
+	return &Validator{printer: message.NewPrinter(language.English)}
+}
+
+// Load returns a validator with the schemas configured in systemPath (the vault's
+// system directory). A missing configuration file means no schemas.
+func Load(systemPath string) (*Validator, error) {
+	cfg, err := LoadConfig(systemPath)
+	if err != nil {
+		return nil, err
+	}
+
+	v := New()
+	c := jsonschema.NewCompiler()
+	for i, rule := range cfg.Schemas {
+		if rule.Pattern == "" || rule.Schema == "" {
+			return nil, fmt.Errorf("invalid schemas config: entry %d needs a pattern and a schema", i)
+		}
+		if !doublestar.ValidatePattern(rule.Pattern) {
+			return nil, fmt.Errorf("invalid schemas config: bad pattern %q", rule.Pattern)
+		}
+		sch, err := c.Compile(filepath.Join(systemPath, filepath.FromSlash(rule.Schema)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema %s: %w", rule.Schema, err)
+		}
+		v.entries = append(v.entries, entry{pattern: rule.Pattern, schema: sch, content: rule.Content})
+	}
+	return v, nil
+}
+
+// Register adds a schema (a JSON Schema document) for the documents whose ID matches pattern.
+func (v *Validator) Register(pattern string, schema []byte, opts ...Option) error {
+	if !doublestar.ValidatePattern(pattern) {
+		return fmt.Errorf("bad schema pattern %q", pattern)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return fmt.Errorf("invalid schema for %s: %w", pattern, err)
+	}
+	url := fmt.Sprintf("loam:///registered/%d.json", len(v.entries))
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(url, doc); err != nil {
+		return fmt.Errorf("invalid schema for %s: %w", pattern, err)
+	}
+	sch, err := c.Compile(url)
+	if err != nil {
+		return fmt.Errorf("invalid schema for %s: %w", pattern, err)
+	}
+
+	e := entry{pattern: pattern, schema: sch}
+	for _, opt := range opts {
+		opt(&e)
+	}
+	v.entries = append(v.entries, e)
+	return nil
+}
+
+// Empty reports whether the validator has no schemas.
+func (v *Validator) Empty() bool {
+	return len(v.entries) == 0
+}
+
+// Matches reports whether any schema applies to the document ID.
+func (v *Validator) Matches(id string) bool {
+	for _, e := range v.entries {
+		if ok, _ := doublestar.Match(e.pattern, id); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the document against every schema matching its ID. It returns
+// a *core.ValidationError listing the violations, or nil.
+func (v *Validator) Validate(doc core.Document) error {
+	var fields []core.FieldError
+	for _, e := range v.entries {
+		if ok, _ := doublestar.Match(e.pattern, doc.ID); !ok {
+			continue
+		}
+		instance, err := instanceOf(doc, e.content)
+		if err != nil {
+			return &core.ValidationError{ID: doc.ID, Errors: []core.FieldError{{Message: err.Error()}}}
+		}
+		err = e.schema.Validate(instance)
+		var verr *jsonschema.ValidationError
+		if errors.As(err, &verr) {
+			fields = v.appendErrors(fields, verr)
+		} else if err != nil {
+			return err
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &core.ValidationError{ID: doc.ID, Errors: fields}
+}
+
+// instanceOf returns the JSON value validated for doc. Going through JSON turns Go
+// values (e.g. []string, time.Time) into JSON types, and keeps numbers as json.Number.
+func instanceOf(doc core.Document, content bool) (any, error) {
+	obj := make(map[string]any, len(doc.Metadata)+1)
+	for k, val := range doc.Metadata {
+		obj[k] = val
+	}
+	if content {
+		obj[ContentKey] = doc.Content
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("metadata is not representable as JSON: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var instance any
+	return instance, dec.Decode(&instance)
+}
+
+// appendErrors flattens the leaves of a schema violation into field errors.
+func (v *Validator) appendErrors(fields []core.FieldError, e *jsonschema.ValidationError) []core.FieldError {
+	if len(e.Causes) > 0 {
+		for _, cause := range e.Causes {
+			fields = v.appendErrors(fields, cause)
+		}
+		return fields
+	}
+
+	field := strings.Join(e.InstanceLocation, ".")
+	switch k := e.ErrorKind.(type) {
+	case *kind.Required:
+		for _, missing := range k.Missing {
+			fields = append(fields, core.FieldError{Field: join(field, missing), Message: "is required"})
+		}
+	case *kind.AdditionalProperties:
+		for _, extra := range k.Properties {
+			fields = append(fields, core.FieldError{Field: join(field, extra), Message: "is not allowed"})
+		}
+	default:
+		fields = append(fields, core.FieldError{Field: field, Message: e.ErrorKind.LocalizedString(v.printer)})
+	}
+	return fields
+}
+
+func join(parent, field string) string {
+	if parent == "" {
+		return field
+	}
+	return parent + "." + field
+}
+
+// Hook returns a core.Hook validating saved documents: SaveDocument rejects an invalid
+// document, transaction commits reject if the final version of a staged document is
+// invalid, and Reconcile reports the invalid documents it finds (they are already on
+// storage). Errors match core.ErrInvalidDocument.
+func (v *Validator) Hook() core.Hook {
+	return func(ctx context.Context, m *core.Mutation, next core.MutationHandler) error {
+		switch m.Kind {
+		case core.MutationSave:
+			if !m.Staged {
+				if err := v.Validate(m.Document); err != nil {
+					return err
+				}
+			}
+		case core.MutationCommit:
+			if err := v.validateChanges(m.Changes); err != nil {
+				return err
+			}
+		case core.MutationReconcile:
+			if err := next(ctx, m); err != nil {
+				return err
+			}
+			return v.validateChanges(m.Changes)
+		}
+		return next(ctx, m)
+	}
+}
+
+// validateChanges validates the final state of the documents saved among changes,
+// joining the errors. Saves later replaced by another save or a delete of the same
+// document (e.g. a draft within a transaction) are not validated.
+func (v *Validator) validateChanges(changes []core.Mutation) error {
+	last := make(map[string]int, len(changes))
+	for i, c := range changes {
+		last[c.Document.ID] = i
+	}
+	var errs []error
+	for i, c := range changes {
+		if c.Kind != core.MutationSave || last[c.Document.ID] != i {
+			continue
+		}
+		if err := v.Validate(c.Document); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Export writes a schema to <systemPath>/schemas/<name>.json and maps pattern to it in
+// the schemas configuration, replacing any schema already mapped to pattern. It shares
+// a schema generated from Go (see For) with the CLI and with editors.
+func Export(systemPath, pattern, name string, schema []byte) error {
+	if !doublestar.ValidatePattern(pattern) {
+		return fmt.Errorf("bad schema pattern %q", pattern)
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("bad schema name %q", name)
+	}
+	if !json.Valid(schema) {
+		return fmt.Errorf("schema %s is not valid JSON", name)
+	}
+
+	cfg, err := LoadConfig(systemPath)
+	if err != nil {
+		return err
+	}
+
+	rel := "schemas/" + name + ".json"
+	if err := os.MkdirAll(filepath.Join(systemPath, "schemas"), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(systemPath, filepath.FromSlash(rel)), schema, 0644); err != nil {
+		return err
+	}
+
+	rule := Rule{Pattern: pattern, Schema: rel}
+	replaced := false
+	for i, r := range cfg.Schemas {
+		if r.Pattern == pattern {
+			rule.Content = r.Content
+			cfg.Schemas[i] = rule
+			replaced = true
+		}
+	}
+	if !replaced {
+		cfg.Schemas = append(cfg.Schemas, rule)
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(systemPath, ConfigName), append(data, '\n'), 0644)
+}
+
+// LoadConfig reads the schemas configured in systemPath. A missing file is an empty configuration.
+func LoadConfig(systemPath string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(filepath.Join(systemPath, ConfigName))
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read schemas config: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid schemas config: %w", err)
+	}
+	return cfg, nil
+}
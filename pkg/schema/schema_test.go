@@ -0,0 +1,177 @@
+package schema_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aretw0/loam/pkg/adapters/fs"
+	"github.com/aretw0/loam/pkg/core"
+	"github.com/aretw0/loam/pkg/schema"
+)
+
+const serviceSchema = `{
+  "type": "object",
+  "required": ["name", "replicas"],
+  "properties": {
+    "name": {"type": "string"},
+    "replicas": {"type": "integer", "minimum": 1},
+    "ports": {"type": "array", "items": {"type": "integer"}}
+  }
+}`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fieldErrors(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var verr *core.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *core.ValidationError, got %v", err)
+	}
+	fields := make(map[string]string)
+	for _, f := range verr.Errors {
+		fields[f.Field] = f.Message
+	}
+	return fields
+}
+
+func TestValidate(t *testing.T) {
+	system := t.TempDir()
+	writeFile(t, filepath.Join(system, "schemas", "service.json"), serviceSchema)
+	writeFile(t, filepath.Join(system, schema.ConfigName), `{"schemas": [{"pattern": "services/*", "schema": "schemas/service.json"}]}`)
+
+	v, err := schema.Load(system)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := core.Document{ID: "services/api", Metadata: core.Metadata{
+		"name":     "api",
+		"replicas": json.Number("3"), // Strict mode.
+		"ports":    []int{80, 443},
+	}}
+	if err := v.Validate(valid); err != nil {
+		t.Errorf("valid document rejected: %v", err)
+	}
+	if err := v.Validate(core.Document{ID: "notes/free-form"}); err != nil {
+		t.Errorf("document without schema rejected: %v", err)
+	}
+
+	err = v.Validate(core.Document{ID: "services/db", Metadata: core.Metadata{
+		"replicas": json.Number("0"),
+		"ports":    []any{80, "ssh"},
+	}})
+	if !errors.Is(err, core.ErrInvalidDocument) {
+		t.Fatalf("expected ErrInvalidDocument, got %v", err)
+	}
+	fields := fieldErrors(t, err)
+	if len(fields) != 3 || fields["name"] != "is required" || fields["replicas"] == "" || fields["ports.1"] == "" {
+		t.Errorf("unexpected field errors: %v", fields)
+	}
+
+	if err := v.Register("notes/*", []byte(`{"properties": {"content": {"minLength": 5}}}`), schema.WithContent()); err != nil {
+		t.Fatal(err)
+	}
+	if fields := fieldErrors(t, v.Validate(core.Document{ID: "notes/a", Content: "hi"})); fields["content"] == "" {
+		t.Errorf("content not validated: %v", fields)
+	}
+}
+
+func TestHook(t *testing.T) {
+	vault := t.TempDir()
+	repo := fs.NewRepository(fs.Config{Path: vault, Gitless: true, SystemDir: ".loam", Strict: true})
+	ctx := context.Background()
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	v := schema.New()
+	if err := v.Register("services/*", []byte(serviceSchema)); err != nil {
+		t.Fatal(err)
+	}
+	svc := core.NewService(repo, core.WithHook(v.Hook()))
+
+	if err := svc.SaveDocument(ctx, "services/api.json", "", core.Metadata{"name": "api", "replicas": 2}); err != nil {
+		t.Fatalf("valid save rejected: %v", err)
+	}
+	if err := svc.SaveDocument(ctx, "services/db.json", "", core.Metadata{"name": "db"}); !errors.Is(err, core.ErrInvalidDocument) {
+		t.Errorf("expected invalid save to be rejected, got %v", err)
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		err := svc.WithTransaction(ctx, func(tx core.Transaction) error {
+			if err := tx.Save(ctx, core.Document{ID: "services/web.json", Metadata: core.Metadata{"name": "web", "replicas": 1}}); err != nil {
+				return err
+			}
+			return tx.Save(ctx, core.Document{ID: "services/cache.json", Metadata: core.Metadata{"replicas": 1}})
+		})
+		if fields := fieldErrors(t, err); fields["name"] != "is required" {
+			t.Errorf("unexpected field errors: %v", fields)
+		}
+		if _, err := svc.GetDocument(ctx, "services/web.json"); err == nil {
+			t.Error("transaction with an invalid document was committed")
+		}
+	})
+
+	t.Run("commit final state", func(t *testing.T) {
+		draft := core.Document{ID: "services/draft.json", Metadata: core.Metadata{"replicas": 1}}
+
+		// An invalid draft replaced by a valid version.
+		err := svc.WithTransaction(ctx, func(tx core.Transaction) error {
+			if err := tx.Save(ctx, draft); err != nil {
+				return err
+			}
+			return tx.Save(ctx, core.Document{ID: draft.ID, Metadata: core.Metadata{"name": "draft", "replicas": 1}})
+		})
+		if err != nil {
+			t.Errorf("replaced draft rejected: %v", err)
+		}
+
+		// An invalid draft deleted before the commit.
+		err = svc.WithTransaction(ctx, func(tx core.Transaction) error {
+			if err := tx.Save(ctx, core.Document{ID: "services/scratch.json", Metadata: core.Metadata{"replicas": 1}}); err != nil {
+				return err
+			}
+			return tx.Delete(ctx, "services/scratch.json")
+		})
+		if err != nil {
+			t.Errorf("deleted draft rejected: %v", err)
+		}
+
+		// A valid document replaced by an invalid version is still rejected.
+		err = svc.WithTransaction(ctx, func(tx core.Transaction) error {
+			if err := tx.Save(ctx, core.Document{ID: draft.ID, Metadata: core.Metadata{"name": "draft", "replicas": 2}}); err != nil {
+				return err
+			}
+			return tx.Save(ctx, draft)
+		})
+		if !errors.Is(err, core.ErrInvalidDocument) {
+			t.Errorf("expected invalid final version to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("reconcile", func(t *testing.T) {
+		if _, err := svc.Reconcile(ctx); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(vault, "services", "edited.json"), `{"name": "edited", "replicas": "many"}`)
+		events, err := svc.Reconcile(ctx)
+		if len(events) != 1 || events[0].ID != "services/edited" {
+			t.Errorf("unexpected reconcile events: %v", events)
+		}
+		var verr *core.ValidationError
+		if !errors.As(err, &verr) || verr.ID != "services/edited" || verr.Errors[0].Field != "replicas" {
+			t.Errorf("expected offline edit to be reported, got %v", err)
+		}
+	})
+}
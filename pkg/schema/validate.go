@@ -0,0 +1,242 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// Validatable is implemented by types with their own validation, run by ValidateStruct
+// after the validate tags. Returning a *core.ValidationError reports per-field errors.
+type Validatable interface {
+	Validate() error
+}
+
+// ValidateStruct checks a Go value against the validate tags of its fields (see
+// Generate) and the Validate methods of the value and its nested structs. Fields
+// are named by their JSON path (e.g. "owner.email", "ports.1"). For the tags:
+//
+//	required        the value must not be the zero value
+//	oneof           the value must be one of the listed values
+//	pattern         the string must match the regular expression
+//	min, max        bound numbers, or the length of strings, slices and maps
+//
+// The zero value of an omitempty field is left out of the document, so only
+// required applies to it. "regex" is accepted as an alias of "pattern".
+func ValidateStruct(v any) []core.FieldError {
+	var fields []core.FieldError
+	check(reflect.ValueOf(v), "", &fields)
+	return fields
+}
+
+func check(v reflect.Value, path string, fields *[]core.FieldError) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+		checkFields(v, path, fields)
+		checkMethod(v, path, fields)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			check(v.Index(i), join(path, strconv.Itoa(i)), fields)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			check(iter.Value(), join(path, iter.Key().String()), fields)
+		}
+	}
+}
+
+// checkFields applies the validate tags of the fields of struct v.
+func checkFields(v reflect.Value, path string, fields *[]core.FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, skip := jsonName(f)
+		if skip {
+			continue
+		}
+		fv := v.Field(i)
+		if name == "" {
+			// Embedded struct: its fields are promoted.
+			check(fv, path, fields)
+			continue
+		}
+
+		field := join(path, name)
+		rules := parseTag(f.Tag.Get("validate"))
+		if fv.IsZero() {
+			if _, ok := rules["required"]; ok {
+				*fields = append(*fields, core.FieldError{Field: field, Message: "is required"})
+				continue
+			}
+			_, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if slices.Contains(strings.Split(opts, ","), "omitempty") || nullable(fv.Kind()) {
+				continue // Left out of the document, or null.
+			}
+		}
+		for _, msg := range checkRules(fv, rules) {
+			*fields = append(*fields, core.FieldError{Field: field, Message: msg})
+		}
+		check(fv, field, fields)
+	}
+}
+
+// nullable reports whether the zero value of a kind encodes as null.
+func nullable(k reflect.Kind) bool {
+	return k == reflect.Pointer || k == reflect.Slice || k == reflect.Map || k == reflect.Interface
+}
+
+// checkMethod runs the Validate method of struct v, if any.
+func checkMethod(v reflect.Value, path string, fields *[]core.FieldError) {
+	if !v.CanInterface() {
+		return // Reached through an unexported embedded struct.
+	}
+	var vv Validatable
+	if val, ok := v.Interface().(Validatable); ok {
+		vv = val
+	} else if v.CanAddr() {
+		vv, _ = v.Addr().Interface().(Validatable)
+	} else if reflect.PointerTo(v.Type()).Implements(reflect.TypeFor[Validatable]()) {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		vv = ptr.Interface().(Validatable)
+	}
+	if vv == nil {
+		return
+	}
+
+	err := vv.Validate()
+	var verr *core.ValidationError
+	switch {
+	case err == nil:
+	case errors.As(err, &verr):
+		for _, fe := range verr.Errors {
+			*fields = append(*fields, core.FieldError{Field: join(path, fe.Field), Message: fe.Message})
+		}
+	default:
+		*fields = append(*fields, core.FieldError{Field: path, Message: err.Error()})
+	}
+}
+
+// checkRules returns the violations of the rules by a field value.
+func checkRules(v reflect.Value, rules map[string]string) []string {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	var msgs []string
+	if values, ok := rules["oneof"]; ok {
+		allowed := strings.Fields(values)
+		if !slices.Contains(allowed, format(v)) {
+			msgs = append(msgs, "must be one of: "+strings.Join(allowed, ", "))
+		}
+	}
+	if pattern, ok := rules["pattern"]; ok && v.Kind() == reflect.String {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("has an invalid pattern %q", pattern))
+		} else if !re.MatchString(v.String()) {
+			msgs = append(msgs, fmt.Sprintf("must match %q", pattern))
+		}
+	}
+	for _, bound := range []string{"min", "max"} {
+		raw, ok := rules[bound]
+		if !ok {
+			continue
+		}
+		limit, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("has an invalid %s %q", bound, raw))
+			continue
+		}
+		n, unit, ok := measure(v)
+		if !ok {
+			continue
+		}
+		if bound == "min" && n < limit {
+			msgs = append(msgs, fmt.Sprintf("must be at least %s%s", raw, unit))
+		}
+		if bound == "max" && n > limit {
+			msgs = append(msgs, fmt.Sprintf("must be at most %s%s", raw, unit))
+		}
+	}
+	return msgs
+}
+
+// format returns the text of a scalar value, as written in a oneof rule.
+func format(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	}
+	return fmt.Sprint(v)
+}
+
+// measure returns what min and max bound for a value: the number itself, or a length.
+func measure(v reflect.Value) (n float64, unit string, ok bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	case reflect.String:
+		if v.Type() == numberType {
+			f, err := strconv.ParseFloat(v.String(), 64)
+			return f, "", err == nil
+		}
+		return float64(utf8.RuneCountInString(v.String())), " characters", true
+	case reflect.Slice, reflect.Array:
+		return float64(v.Len()), " items", true
+	case reflect.Map:
+		return float64(v.Len()), " entries", true
+	}
+	return 0, "", false
+}
+
+var patterns sync.Map // string -> *regexp.Regexp
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
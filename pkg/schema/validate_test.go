@@ -0,0 +1,67 @@
+package schema_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aretw0/loam/pkg/schema"
+)
+
+type Contact struct {
+	Email string `json:"email" validate:"required,pattern=^[^@]+@[^@,]+$"`
+}
+
+type Order struct {
+	ID       string            `json:"id" validate:"required,min=3"`
+	Status   string            `json:"status" validate:"oneof=open closed"`
+	Priority int               `json:"priority,omitempty" validate:"oneof=1 2 3"`
+	Total    float64           `json:"total" validate:"min=0.01"`
+	Contact  *Contact          `json:"contact"`
+	Lines    []Line            `json:"lines" validate:"min=1"`
+	Extra    map[string]string `json:"extra,omitempty" validate:"max=1"`
+}
+
+type Line struct {
+	SKU string `json:"sku" validate:"required"`
+	Qty int    `json:"qty" validate:"min=1"`
+}
+
+func (l *Line) Validate() error {
+	if l.Qty > 100 {
+		return errors.New("quantity over the order limit")
+	}
+	return nil
+}
+
+func TestValidateStruct(t *testing.T) {
+	valid := Order{ID: "o-1", Status: "open", Total: 9.5, Lines: []Line{{SKU: "a", Qty: 1}}}
+	if fields := schema.ValidateStruct(valid); len(fields) != 0 {
+		t.Errorf("valid order rejected: %v", fields)
+	}
+
+	invalid := Order{
+		ID:       "o",
+		Status:   "lost",
+		Priority: 7,
+		Contact:  &Contact{Email: "a@b,c"},
+		Lines:    []Line{{Qty: 0}, {SKU: "b", Qty: 101}},
+		Extra:    map[string]string{"a": "1", "b": "2"},
+	}
+	got := make(map[string]int)
+	for _, fe := range schema.ValidateStruct(&invalid) {
+		got[fe.Field]++
+	}
+	want := map[string]int{
+		"id": 1, "status": 1, "priority": 1, "total": 1, "contact.email": 1,
+		"lines.0.sku": 1, "lines.0.qty": 1, "lines.1": 1, "extra": 1,
+	}
+	if len(got) != len(want) {
+		t.Errorf("field errors = %v, want %v", got, want)
+	}
+	for field, n := range want {
+		if got[field] != n {
+			t.Errorf("field errors = %v, want %v", got, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package scripthooks runs the executable hooks configured in a vault, in the style of git hooks.
+//
+// Hooks are executables in <SystemDir>/hooks named after the point they run at:
+//
+//	pre-save   before a document is saved or deleted; may veto or rewrite it.
+//	post-save  after a save or delete is applied (after the commit, in transactions).
+//	pre-sync   before the vault is synchronized with its remote; may veto it.
+//	post-sync  after a successful synchronization.
+//
+// A hook receives an Input as JSON on stdin and runs in the vault root. A non-zero exit
+// status from a pre-* hook vetoes the operation, with the hook's stderr as the reason.
+// A pre-save hook may rewrite the document by printing an Output as JSON on stdout.
+// Failures of post-* hooks are logged; the operation already happened.
+package scripthooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// Hook names, which are also the executable names inside the hooks directory.
+const (
+	PreSave  = "pre-save"
+	PostSave = "post-save"
+	PreSync  = "pre-sync"
+	PostSync = "post-sync"
+)
+
+// DirName is the directory, inside the system directory, holding the hooks.
+const DirName = "hooks"
+
+// DefaultTimeout bounds the run of a single hook.
+const DefaultTimeout = 10 * time.Second
+
+// ErrRejected is returned (wrapped) when a pre-* hook vetoes an operation, fails or times out.
+var ErrRejected = errors.New("rejected by hook")
+
+// Input is the JSON a hook receives on stdin.
+type Input struct {
+	Hook      string        `json:"hook"`
+	Vault     string        `json:"vault"`               // Absolute path of the vault root.
+	Operation string        `json:"operation,omitempty"` // "save" or "delete"; empty for sync hooks.
+	ID        string        `json:"id,omitempty"`
+	Content   string        `json:"content,omitempty"`
+	Metadata  core.Metadata `json:"metadata,omitempty"`
+	Staged    bool          `json:"staged,omitempty"` // pre-save inside a transaction: the change is applied on commit.
+}
+
+// Output is the JSON a pre-save hook may print to rewrite the document.
+// Fields left out keep their value.
+type Output struct {
+	ID       *string       `json:"id,omitempty"`
+	Content  *string       `json:"content,omitempty"`
+	Metadata core.Metadata `json:"metadata,omitempty"`
+}
+
+// Option configures a Runner.
+type Option func(*Runner)
+
+// WithTimeout sets how long a hook may run before it is killed (DefaultTimeout if unset).
+// A pre-* hook that times out vetoes the operation.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Runner) {
+		if d > 0 {
+			r.timeout = d
+		}
+	}
+}
+
+// WithLogger sets the logger for post-* hook failures.
+func WithLogger(logger *slog.Logger) Option {
+	return func(r *Runner) {
+		r.logger = logger
+	}
+}
+
+// Runner runs the hooks of a vault.
+type Runner struct {
+	vault   string
+	dir     string
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+// New creates a runner for the hooks in systemPath/hooks, run in the vault at vaultPath.
+func New(vaultPath, systemPath string, opts ...Option) *Runner {
+	r := &Runner{
+		vault:   vaultPath,
+		dir:     filepath.Join(systemPath, DirName),
+		timeout: DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Hook returns the core.Hook running pre-save and post-save around the service's
+// saves and deletes. Inside transactions, pre-save runs as changes are staged and
+// post-save runs for each change after the commit.
+func (r *Runner) Hook() core.Hook {
+	return func(ctx context.Context, m *core.Mutation, next core.MutationHandler) error {
+		switch m.Kind {
+		case core.MutationSave, core.MutationDelete:
+			if err := r.preSave(ctx, m); err != nil {
+				return err
+			}
+			if err := next(ctx, m); err != nil {
+				return err
+			}
+			if !m.Staged {
+				r.postSave(ctx, *m)
+			}
+			return nil
+		case core.MutationCommit:
+			if err := next(ctx, m); err != nil {
+				return err
+			}
+			for _, change := range m.Changes {
+				r.postSave(ctx, change)
+			}
+			return nil
+		}
+		return next(ctx, m)
+	}
+}
+
+// Sync runs sync between the pre-sync and post-sync hooks.
+func (r *Runner) Sync(ctx context.Context, sync func(context.Context) error) error {
+	if _, err := r.run(ctx, PreSync, Input{}); err != nil {
+		return fmt.Errorf("%w: %w", ErrRejected, err)
+	}
+	if err := sync(ctx); err != nil {
+		return err
+	}
+	if _, err := r.run(ctx, PostSync, Input{}); err != nil {
+		r.logFailure(PostSync, err)
+	}
+	return nil
+}
+
+func (r *Runner) preSave(ctx context.Context, m *core.Mutation) error {
+	out, err := r.run(ctx, PreSave, mutationInput(*m))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRejected, err)
+	}
+	if m.Kind != core.MutationSave || len(bytes.TrimSpace(out)) == 0 {
+		return nil
+	}
+
+	var rewrite Output
+	if err := json.Unmarshal(out, &rewrite); err != nil {
+		return fmt.Errorf("%s hook printed invalid JSON: %w", PreSave, err)
+	}
+	if rewrite.ID != nil {
+		m.Document.ID = *rewrite.ID
+	}
+	if rewrite.Content != nil {
+		m.Document.Content = *rewrite.Content
+	}
+	if rewrite.Metadata != nil {
+		m.Document.Metadata = rewrite.Metadata
+	}
+	return nil
+}
+
+func (r *Runner) postSave(ctx context.Context, m core.Mutation) {
+	m.Staged = false
+	if _, err := r.run(ctx, PostSave, mutationInput(m)); err != nil {
+		r.logFailure(PostSave, err)
+	}
+}
+
+func mutationInput(m core.Mutation) Input {
+	return Input{
+		Operation: string(m.Kind),
+		ID:        m.Document.ID,
+		Content:   m.Document.Content,
+		Metadata:  m.Document.Metadata,
+		Staged:    m.Staged,
+	}
+}
+
+// run executes a hook, returning its stdout. A missing (or, outside Windows,
+// non-executable) hook is skipped.
+func (r *Runner) run(ctx context.Context, name string, in Input) ([]byte, error) {
+	path := filepath.Join(r.dir, name)
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || (runtime.GOOS != "windows" && info.Mode()&0111 == 0) {
+		return nil, nil
+	}
+
+	in.Hook = name
+	in.Vault = r.vault
+	stdin, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s hook input: %w", name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Dir = r.vault
+	cmd.WaitDelay = time.Second // Don't wait on children still holding the pipes after a timeout.
+	cmd.Env = append(os.Environ(), "LOAM_HOOK="+name, "LOAM_VAULT="+r.vault, "LOAM_ID="+in.ID)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("%s timed out after %s", name, r.timeout)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			reason := strings.TrimSpace(stderr.String())
+			if reason == "" {
+				reason = exitErr.String()
+			}
+			return nil, fmt.Errorf("%s: %s", name, reason)
+		}
+		return nil, fmt.Errorf("failed to run %s hook: %w", name, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func (r *Runner) logFailure(name string, err error) {
+	if r.logger != nil {
+		r.logger.Warn("hook failed", "hook", name, "err", err)
+	}
+}
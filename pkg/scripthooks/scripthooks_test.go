@@ -0,0 +1,150 @@
+package scripthooks_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aretw0/loam/pkg/adapters/fs"
+	"github.com/aretw0/loam/pkg/core"
+	"github.com/aretw0/loam/pkg/scripthooks"
+)
+
+func writeHook(t *testing.T, vault, name, script string) {
+	t.Helper()
+	dir := filepath.Join(vault, ".loam", scripthooks.DirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setup(t *testing.T, opts ...scripthooks.Option) (*core.Service, *scripthooks.Runner, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts use /bin/sh")
+	}
+	vault := t.TempDir()
+	repo := fs.NewRepository(fs.Config{Path: vault, Gitless: true, SystemDir: ".loam"})
+	if err := repo.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	runner := scripthooks.New(vault, filepath.Join(vault, ".loam"), opts...)
+	return core.NewService(repo, core.WithHook(runner.Hook())), runner, vault
+}
+
+func postLog(t *testing.T, vault string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(vault, ".loam", "post.log"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestScriptHooks(t *testing.T) {
+	svc, _, vault := setup(t)
+	ctx := context.Background()
+
+	writeHook(t, vault, scripthooks.PreSave, `input=$(cat)
+case "$input" in
+  *'"operation":"delete"'*) exit 0 ;;
+  *forbidden*) echo "forbidden documents are not allowed" >&2; exit 1 ;;
+esac
+echo '{"metadata": {"checked": "yes"}}'
+`)
+	writeHook(t, vault, scripthooks.PostSave, `cat > /dev/null
+echo "$LOAM_ID" >> "$LOAM_VAULT/.loam/post.log"
+`)
+
+	t.Run("rewrite", func(t *testing.T) {
+		if err := svc.SaveDocument(ctx, "notes/a.md", "hello", nil); err != nil {
+			t.Fatal(err)
+		}
+		doc, err := svc.GetDocument(ctx, "notes/a.md")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Metadata["checked"] != "yes" || doc.Content != "hello" {
+			t.Errorf("document not rewritten by pre-save: %+v", doc)
+		}
+		if log := postLog(t, vault); log != "notes/a.md\n" {
+			t.Errorf("post-save log = %q", log)
+		}
+	})
+
+	t.Run("veto", func(t *testing.T) {
+		err := svc.SaveDocument(ctx, "notes/forbidden.md", "x", nil)
+		if !errors.Is(err, scripthooks.ErrRejected) || !strings.Contains(err.Error(), "forbidden documents are not allowed") {
+			t.Fatalf("expected rejection with the hook's stderr, got %v", err)
+		}
+		if _, err := svc.GetDocument(ctx, "notes/forbidden.md"); err == nil {
+			t.Error("vetoed document was saved")
+		}
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		os.Remove(filepath.Join(vault, ".loam", "post.log"))
+		err := svc.WithTransaction(ctx, func(tx core.Transaction) error {
+			if err := tx.Save(ctx, core.Document{ID: "notes/b.md", Content: "b"}); err != nil {
+				return err
+			}
+			if err := tx.Delete(ctx, "notes/a.md"); err != nil {
+				return err
+			}
+			if log := postLog(t, vault); log != "" {
+				t.Errorf("post-save ran before the commit: %q", log)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if log := postLog(t, vault); log != "notes/b.md\nnotes/a.md\n" {
+			t.Errorf("post-save log = %q", log)
+		}
+		doc, err := svc.GetDocument(ctx, "notes/b.md")
+		if err != nil || doc.Metadata["checked"] != "yes" {
+			t.Errorf("staged save not rewritten: %+v, %v", doc, err)
+		}
+	})
+}
+
+func TestScriptHooks_Sync(t *testing.T) {
+	_, runner, vault := setup(t, scripthooks.WithTimeout(200*time.Millisecond))
+	ctx := context.Background()
+
+	synced := 0
+	sync := func(context.Context) error {
+		synced++
+		return nil
+	}
+
+	writeHook(t, vault, scripthooks.PostSync, `echo synced >> "$LOAM_VAULT/.loam/post.log"`)
+	if err := runner.Sync(ctx, sync); err != nil {
+		t.Fatal(err)
+	}
+	if synced != 1 || postLog(t, vault) != "synced\n" {
+		t.Errorf("sync ran %d times, post-sync log %q", synced, postLog(t, vault))
+	}
+
+	writeHook(t, vault, scripthooks.PreSync, "sleep 5\n")
+	start := time.Now()
+	err := runner.Sync(ctx, sync)
+	if !errors.Is(err, scripthooks.ErrRejected) || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout rejection, got %v", err)
+	}
+	if synced != 1 {
+		t.Error("sync ran after pre-sync timed out")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("timed out hook held the sync for %s", elapsed)
+	}
+}
@@ -0,0 +1,40 @@
+package typed
+
+import (
+	"context"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// Event is a change event with typed versions of the document.
+// Old and New are only set when the repository emits rich events (see loam.WithRichEvents);
+// Old is built from the previous metadata and has no content.
+type Event[T any] struct {
+	core.Event
+	Old *DocumentModel[T]
+	New *DocumentModel[T]
+}
+
+// typedEvents converts a core event stream. Payloads that do not fit T (or fail
+// validation, unless lenient) are left nil.
+func typedEvents[T any](ctx context.Context, in <-chan core.Event, saver Saver[T], opts options) <-chan Event[T] {
+	out := make(chan Event[T], cap(in))
+	go func() {
+		defer close(out)
+		for e := range in {
+			te := Event[T]{Event: e}
+			if e.Previous != nil {
+				te.Old, _ = fromCore(*e.Previous, saver, opts)
+			}
+			if e.Document != nil {
+				te.New, _ = fromCore(*e.Document, saver, opts)
+			}
+			select {
+			case out <- te:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,236 @@
+package typed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// VersionKey is the metadata key holding the schema version of a document.
+// Documents without it are at version 0.
+const VersionKey = "schema_version"
+
+// MigrationStep upgrades the metadata of a document by one schema version. It may
+// modify and return the map it is given, which is a copy of the stored metadata.
+type MigrationStep func(map[string]any) (map[string]any, error)
+
+// Migrations upgrades the documents of a type as its Go struct evolves. The Nth
+// registered step upgrades documents from version N-1 to N, so the current
+// version is the number of steps.
+type Migrations struct {
+	pattern string
+	steps   []MigrationStep
+}
+
+// NewMigrations creates an empty migration registry for the documents whose IDs
+// match pattern (a doublestar glob, like "tickets/**"). An empty pattern matches
+// every document.
+func NewMigrations(pattern string) *Migrations {
+	return &Migrations{pattern: pattern}
+}
+
+// Register appends the step upgrading documents to the next version.
+func (m *Migrations) Register(step MigrationStep) *Migrations {
+	m.steps = append(m.steps, step)
+	return m
+}
+
+// Version returns the current schema version: the number of registered steps.
+func (m *Migrations) Version() int {
+	return len(m.steps)
+}
+
+// Matches reports whether the document id belongs to the migrated type.
+func (m *Migrations) Matches(id string) bool {
+	if m.pattern == "" {
+		return true
+	}
+	ok, _ := doublestar.Match(m.pattern, id)
+	return ok
+}
+
+// Upgrade runs the steps a document needs to reach the current version. It
+// returns the upgraded metadata, stamped with the version, and whether any step
+// ran. Documents that do not match the pattern are returned as they are.
+func (m *Migrations) Upgrade(id string, metadata map[string]any) (map[string]any, bool, error) {
+	version, outdated, err := m.outdated(id, metadata)
+	if err != nil {
+		return nil, false, err
+	}
+	if !outdated {
+		return metadata, false, nil
+	}
+
+	upgraded, _ := deepCopy(metadata).(map[string]any)
+	if upgraded == nil {
+		upgraded = make(map[string]any)
+	}
+	for ; version < m.Version(); version++ {
+		if upgraded, err = m.steps[version](upgraded); err != nil {
+			return nil, false, fmt.Errorf("failed to migrate %s to version %d: %w", id, version+1, err)
+		}
+		if upgraded == nil {
+			upgraded = make(map[string]any)
+		}
+	}
+	upgraded[VersionKey] = m.Version()
+	return upgraded, true, nil
+}
+
+// outdated reads the version of a document of the type and reports whether it is
+// behind the current one, without running any step.
+func (m *Migrations) outdated(id string, metadata map[string]any) (int, bool, error) {
+	if !m.Matches(id) {
+		return 0, false, nil
+	}
+	version, err := versionOf(metadata)
+	if err != nil {
+		return 0, false, fmt.Errorf("document %s: %w", id, err)
+	}
+	if version > m.Version() {
+		return 0, false, fmt.Errorf("document %s has schema version %d, newer than %d", id, version, m.Version())
+	}
+	return version, version < m.Version(), nil
+}
+
+// WithMigrations upgrades the documents matching m to its current version as they
+// are loaded, and stamps saved documents with that version (in VersionKey).
+func WithMigrations(m *Migrations) Option {
+	return func(o *options) {
+		o.migrations = m
+	}
+}
+
+// upgrade returns the metadata of a loaded document at the current version.
+func (o options) upgrade(doc core.Document) (map[string]any, error) {
+	if o.migrations == nil {
+		return doc.Metadata, nil
+	}
+	metadata, _, err := o.migrations.Upgrade(doc.ID, doc.Metadata)
+	return metadata, err
+}
+
+// stamp sets the current version on the metadata of a document being saved.
+func (o options) stamp(id string, metadata map[string]any) {
+	if o.migrations != nil && metadata != nil && o.migrations.Matches(id) {
+		metadata[VersionKey] = o.migrations.Version()
+	}
+}
+
+// Migrate rewrites every document of the type that is behind the current version,
+// in a single transaction (one commit in versioned vaults). It returns the IDs of
+// the migrated documents; if any step fails, nothing is written.
+func Migrate(ctx context.Context, svc *core.Service, m *Migrations) ([]string, error) {
+	docs, err := svc.ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only versions are compared here: the steps run once per document, inside
+	// the transaction, so steps with side effects are not repeated.
+	var outdated []string
+	for _, doc := range docs {
+		_, behind, err := m.outdated(doc.ID, doc.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		if behind {
+			outdated = append(outdated, doc.ID)
+		}
+	}
+	if len(outdated) == 0 {
+		return nil, nil
+	}
+
+	if reason, _ := ctx.Value(core.ChangeReasonKey).(string); reason == "" {
+		reason = fmt.Sprintf("migrate %d document(s) to schema version %d", len(outdated), m.Version())
+		ctx = context.WithValue(ctx, core.ChangeReasonKey, reason)
+	}
+	err = svc.WithTransaction(ctx, func(tx core.Transaction) error {
+		for _, id := range outdated {
+			// Listed documents may come from the index, without their content.
+			doc, err := tx.Get(ctx, id)
+			if err != nil {
+				return err
+			}
+			if doc.Metadata, _, err = m.Upgrade(id, doc.Metadata); err != nil {
+				return err
+			}
+			if err := tx.Save(ctx, doc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outdated, nil
+}
+
+// errNoMigrations is returned by Migrate methods of wrappers created without WithMigrations.
+var errNoMigrations = errors.New("no migrations configured (see WithMigrations)")
+
+// versionOf reads the schema version of a document's metadata.
+func versionOf(metadata map[string]any) (int, error) {
+	raw, ok := metadata[VersionKey]
+	if !ok || raw == nil {
+		return 0, nil
+	}
+	var n float64
+	switch v := raw.(type) {
+	case int:
+		n = float64(v)
+	case int64:
+		n = float64(v)
+	case uint64:
+		n = float64(v)
+	case float64:
+		n = v
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", VersionKey, v)
+		}
+		n = f
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", VersionKey, v)
+		}
+		n = f
+	default:
+		return 0, fmt.Errorf("invalid %s %v", VersionKey, raw)
+	}
+	if n < 0 || n != math.Trunc(n) {
+		return 0, fmt.Errorf("invalid %s %v", VersionKey, raw)
+	}
+	return int(n), nil
+}
+
+// deepCopy copies the maps and slices of decoded metadata, so that steps do not
+// modify documents held by the repository (e.g. its cache).
+func deepCopy(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for k, e := range v {
+			c[k] = deepCopy(e)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, e := range v {
+			c[i] = deepCopy(e)
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,249 @@
+package typed
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// Field is a field of T holding values of type V, selected by Where. Its conditions
+// accept only values of type V, and it is addressed in queries by its JSON path.
+type Field[T, V any] struct {
+	path string
+	err  error
+}
+
+// Where selects the field of T that sel returns a pointer to, checked at compile time:
+//
+//	typed.Where(func(t *Ticket) *string { return &t.Status }).Eq("open")
+//
+// sel is called once on a zero T, so it must only take the address of a field
+// (nested structs are fine, but not through pointers, slices or maps).
+func Where[T, V any](sel func(*T) *V) Field[T, V] {
+	path, err := fieldPath(sel)
+	return Field[T, V]{path: path, err: err}
+}
+
+// Path returns the JSON path of the field (e.g. "owner.email").
+func (f Field[T, V]) Path() string {
+	return f.path
+}
+
+// Eq matches documents whose field equals v.
+func (f Field[T, V]) Eq(v V) Condition[T] { return f.condition(core.OpEq, v) }
+
+// Ne matches documents whose field differs from v, or is missing.
+func (f Field[T, V]) Ne(v V) Condition[T] { return f.condition(core.OpNe, v) }
+
+// Lt matches documents whose field is less than v.
+func (f Field[T, V]) Lt(v V) Condition[T] { return f.condition(core.OpLt, v) }
+
+// Lte matches documents whose field is less than or equal to v.
+func (f Field[T, V]) Lte(v V) Condition[T] { return f.condition(core.OpLte, v) }
+
+// Gt matches documents whose field is greater than v.
+func (f Field[T, V]) Gt(v V) Condition[T] { return f.condition(core.OpGt, v) }
+
+// Gte matches documents whose field is greater than or equal to v.
+func (f Field[T, V]) Gte(v V) Condition[T] { return f.condition(core.OpGte, v) }
+
+// Contains matches documents whose field is a string containing v, or a list
+// with an element equal to v.
+func (f Field[T, V]) Contains(v any) Condition[T] { return f.condition(core.OpContains, v) }
+
+// Exists matches documents where the field is present.
+func (f Field[T, V]) Exists() Condition[T] {
+	return Condition[T]{cond: core.Condition{Field: f.path, Op: core.OpExists}, err: f.err}
+}
+
+func (f Field[T, V]) condition(op core.Operator, v any) Condition[T] {
+	if f.err != nil {
+		return Condition[T]{err: f.err}
+	}
+	value, err := jsonValue(v)
+	if err != nil {
+		return Condition[T]{err: fmt.Errorf("invalid value for %s: %w", f.path, err)}
+	}
+	return Condition[T]{cond: core.Condition{Field: f.path, Op: op, Value: value}}
+}
+
+// Condition filters documents of type T on one of its fields.
+type Condition[T any] struct {
+	cond core.Condition
+	err  error
+}
+
+// Order sorts query results by a field of T. See Asc and Desc.
+type Order[T any] struct {
+	field string
+	err   error
+}
+
+// Asc sorts by the field sel returns a pointer to, in ascending order.
+func Asc[T, V any](sel func(*T) *V) Order[T] {
+	path, err := fieldPath(sel)
+	return Order[T]{field: path, err: err}
+}
+
+// Desc sorts by the field sel returns a pointer to, in descending order.
+func Desc[T, V any](sel func(*T) *V) Order[T] {
+	path, err := fieldPath(sel)
+	return Order[T]{field: "-" + path, err: err}
+}
+
+// Query is a core.Query built from typed fields of T:
+//
+//	q := typed.NewQuery[Ticket]().
+//		Pattern("tickets/**").
+//		Where(typed.Where(func(t *Ticket) *string { return &t.Status }).Eq("open")).
+//		OrderBy(typed.Desc(func(t *Ticket) *int { return &t.Priority })).
+//		Limit(10)
+//	docs, err := tickets.Find(ctx, q)
+//
+// Conditions are evaluated on the metadata upgraded by WithMigrations, so they
+// also match documents not migrated yet. Only the results are decoded to T
+// (and validated).
+type Query[T any] struct {
+	q   core.Query
+	err error
+}
+
+// NewQuery creates a query matching every document.
+func NewQuery[T any]() *Query[T] {
+	return &Query[T]{}
+}
+
+// Pattern restricts the query to IDs matching a glob (e.g. "tickets/**").
+func (q *Query[T]) Pattern(pattern string) *Query[T] {
+	q.q.Pattern = pattern
+	return q
+}
+
+// Where adds conditions; all of them must hold.
+func (q *Query[T]) Where(conds ...Condition[T]) *Query[T] {
+	for _, c := range conds {
+		if c.err != nil && q.err == nil {
+			q.err = c.err
+		}
+		q.q.Where = append(q.q.Where, c.cond)
+	}
+	return q
+}
+
+// OrderBy sorts the results. Documents missing the field go last.
+func (q *Query[T]) OrderBy(o Order[T]) *Query[T] {
+	if o.err != nil && q.err == nil {
+		q.err = o.err
+	}
+	q.q.OrderBy = o.field
+	return q
+}
+
+// Limit caps the number of results; 0 means no limit.
+func (q *Query[T]) Limit(n int) *Query[T] {
+	q.q.Limit = n
+	return q
+}
+
+// Build returns the core.Query, e.g. for core.Service.WatchQuery, or the error of
+// an invalid field selector or value.
+func (q *Query[T]) Build() (core.Query, error) {
+	if q == nil {
+		return core.Query{}, nil
+	}
+	return q.q, q.err
+}
+
+// fieldPath returns the JSON path of the field of T that sel points to.
+func fieldPath[T, V any](sel func(*T) *V) (path string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid field selector: %v", r)
+		}
+	}()
+
+	root := new(T)
+	target := sel(root)
+	if target == nil {
+		return "", fmt.Errorf("invalid field selector: returned nil")
+	}
+	v := reflect.ValueOf(root).Elem()
+	if path, ok := findField(v, reflect.ValueOf(target).Pointer(), reflect.TypeFor[V](), ""); ok {
+		if path == core.IDField {
+			return "", fmt.Errorf("invalid field selector: %s is reserved for the document ID", path)
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("invalid field selector: not a JSON field of %s", v.Type())
+}
+
+// findField searches struct v for the field at addr with type want. A struct and its
+// first field share their address, so the type tells them apart.
+func findField(v reflect.Value, addr uintptr, want reflect.Type, path string) (string, bool) {
+	if path != "" && v.Type() == want && v.UnsafeAddr() == addr {
+		return path, true
+	}
+	if v.Kind() != reflect.Struct {
+		return "", false
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, skip := jsonFieldName(f)
+		if skip {
+			continue
+		}
+		sub := path
+		if name != "" {
+			sub = join(path, name)
+		}
+		if found, ok := findField(v.Field(i), addr, want, sub); ok {
+			return found, true
+		}
+	}
+	return "", false
+}
+
+// jsonFieldName returns the JSON name of a field following encoding/json, or ""
+// for an embedded struct whose fields are promoted.
+func jsonFieldName(f reflect.StructField) (name string, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+		return "", false
+	}
+	if !f.IsExported() {
+		return "", true
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name, false
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// jsonValue converts a Go value to its form in decoded metadata (e.g. a time.Time
+// to its RFC 3339 string), so that it compares with the stored values.
+func jsonValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
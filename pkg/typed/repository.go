@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/aretw0/loam/pkg/core"
@@ -16,6 +17,9 @@ type DocumentModel[T any] struct {
 	Content string
 	Data    T        // The typed metadata/entities
 	Saver   Saver[T] // Active Record reference interface
+
+	// Diagnostics lists the field errors of a document loaded with WithLenientValidation.
+	Diagnostics []core.FieldError
 }
 
 // Saver interface avoids circular dependencies or tight coupling with Repository/Service structs.
@@ -34,15 +38,20 @@ func (d *DocumentModel[T]) Save(ctx context.Context) error {
 // Repository wraps a core.Repository to provide type-safe access.
 type Repository[T any] struct {
 	repo core.Repository
+	opts options
 }
 
 // NewRepository creates a new type-safe wrapper around an existing repository.
-func NewRepository[T any](repo core.Repository) *Repository[T] {
-	return &Repository[T]{repo: repo}
+func NewRepository[T any](repo core.Repository, opts ...Option) *Repository[T] {
+	return &Repository[T]{repo: repo, opts: newOptions(opts)}
 }
 
 // Save persists a typed document.
 func (r *Repository[T]) Save(ctx context.Context, doc *DocumentModel[T]) error {
+	if err := r.opts.check(doc.ID, doc.Data); err != nil {
+		return err
+	}
+
 	// 1. Marshal Data to JSON
 	dataBytes, err := json.Marshal(doc.Data)
 	if err != nil {
@@ -56,6 +65,7 @@ func (r *Repository[T]) Save(ctx context.Context, doc *DocumentModel[T]) error {
 	if err := decoder.Decode(&metadata); err != nil {
 		return fmt.Errorf("failed to convert typed data to map: %w", err)
 	}
+	r.opts.stamp(doc.ID, metadata)
 
 	// 3. Create core.Document
 	coreDoc := core.Document{
@@ -79,7 +89,7 @@ func (r *Repository[T]) Get(ctx context.Context, id string) (*DocumentModel[T],
 	if err != nil {
 		return nil, err
 	}
-	return fromCore(coreDoc, r)
+	return fromCore(coreDoc, r, r.opts)
 }
 
 // List returns all documents converted to the typed model.
@@ -90,15 +100,20 @@ func (r *Repository[T]) List(ctx context.Context) ([]*DocumentModel[T], error) {
 	}
 
 	// Hydration N+1 removed: Core List now returns full Metadata from Cache.
-	result := make([]*DocumentModel[T], 0, len(coreDocs))
-	for _, d := range coreDocs {
-		model, err := fromCore(d, r)
-		if err != nil {
-			return nil, fmt.Errorf("failed to process document %s: %w", d.ID, err)
-		}
-		result = append(result, model)
+	return fromCoreAll(coreDocs, r, r.opts)
+}
+
+// Find returns the documents matching a typed query.
+func (r *Repository[T]) Find(ctx context.Context, q *Query[T]) ([]*DocumentModel[T], error) {
+	cq, err := q.Build()
+	if err != nil {
+		return nil, err
 	}
-	return result, nil
+	coreDocs, err := r.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return query(coreDocs, cq, r, r.opts)
 }
 
 // Delete removes a document by ID.
@@ -106,17 +121,35 @@ func (r *Repository[T]) Delete(ctx context.Context, id string) error {
 	return r.repo.Delete(ctx, id)
 }
 
+// Migrate rewrites the documents behind the version of the WithMigrations
+// registry in a single transaction, returning their IDs. See Migrate.
+func (r *Repository[T]) Migrate(ctx context.Context) ([]string, error) {
+	if r.opts.migrations == nil {
+		return nil, errNoMigrations
+	}
+	return Migrate(ctx, core.NewService(r.repo), r.opts.migrations)
+}
+
 // Watch observes changes in the repository.
-func (r *Repository[T]) Watch(ctx context.Context, pattern string) (<-chan core.Event, error) {
+// With rich events enabled, each event carries the typed old and new values.
+func (r *Repository[T]) Watch(ctx context.Context, pattern string) (<-chan Event[T], error) {
 	if w, ok := r.repo.(core.Watchable); ok {
-		return w.Watch(ctx, pattern)
+		events, err := w.Watch(ctx, pattern)
+		if err != nil {
+			return nil, err
+		}
+		return typedEvents[T](ctx, events, r, r.opts), nil
 	}
 	return nil, fmt.Errorf("repository does not support watching")
 }
 
-// Helper to convert core.Document to DocumentModel
-func fromCore[T any](coreDoc core.Document, saver Saver[T]) (*DocumentModel[T], error) {
-	dataBytes, err := json.Marshal(coreDoc.Metadata)
+// Helper to convert core.Document to DocumentModel, migrating and validating it if enabled.
+func fromCore[T any](coreDoc core.Document, saver Saver[T], opts options) (*DocumentModel[T], error) {
+	metadata, err := opts.upgrade(coreDoc)
+	if err != nil {
+		return nil, err
+	}
+	dataBytes, err := json.Marshal(metadata)
 	if err != nil {
 		return nil, fmt.Errorf("metadata marshal failed: %w", err)
 	}
@@ -126,10 +159,51 @@ func fromCore[T any](coreDoc core.Document, saver Saver[T]) (*DocumentModel[T],
 		return nil, fmt.Errorf("unmarshal to target type failed: %w", err)
 	}
 
-	return &DocumentModel[T]{
+	model := &DocumentModel[T]{
 		ID:      coreDoc.ID,
 		Content: coreDoc.Content,
 		Data:    data,
 		Saver:   saver,
-	}, nil
+	}
+	if err := checkLoaded(opts, model); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// query returns the documents matching q, decoded. Conditions are evaluated on
+// the metadata upgraded by the migrations, as the field paths come from the
+// current T, so documents not migrated yet are matched too.
+func query[T any](coreDocs []core.Document, q core.Query, saver Saver[T], opts options) ([]*DocumentModel[T], error) {
+	candidates := core.Query{Pattern: q.Pattern}.Apply(coreDocs)
+	for i, d := range candidates {
+		metadata, err := opts.upgrade(d)
+		if err != nil {
+			return nil, fmt.Errorf("failed to process document %s: %w", d.ID, err)
+		}
+		candidates[i].Metadata = metadata
+	}
+	return fromCoreAll(q.Apply(candidates), saver, opts)
+}
+
+// fromCoreAll converts a list of core documents. Validation errors are collected
+// so that every invalid document is reported.
+func fromCoreAll[T any](coreDocs []core.Document, saver Saver[T], opts options) ([]*DocumentModel[T], error) {
+	result := make([]*DocumentModel[T], 0, len(coreDocs))
+	var invalid []error
+	for _, d := range coreDocs {
+		model, err := fromCore(d, saver, opts)
+		if errors.Is(err, core.ErrInvalidDocument) {
+			invalid = append(invalid, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to process document %s: %w", d.ID, err)
+		}
+		result = append(result, model)
+	}
+	if len(invalid) > 0 {
+		return nil, errors.Join(invalid...)
+	}
+	return result, nil
 }
@@ -10,12 +10,13 @@ import (
 
 // Service wraps a core.Service to provide type-safe access and business logic support.
 type Service[T any] struct {
-	svc *core.Service
+	svc  *core.Service
+	opts options
 }
 
 // NewService creates a new typed service wrapper.
-func NewService[T any](svc *core.Service) *Service[T] {
-	return &Service[T]{svc: svc}
+func NewService[T any](svc *core.Service, opts ...Option) *Service[T] {
+	return &Service[T]{svc: svc, opts: newOptions(opts)}
 }
 
 // Save persists a typed document using the core Service (including validation/transactions).
@@ -24,8 +25,13 @@ func (s *Service[T]) Save(ctx context.Context, doc *DocumentModel[T]) error {
 }
 
 // Watch observes changes in the repository.
-func (s *Service[T]) Watch(ctx context.Context, pattern string) (<-chan core.Event, error) {
-	return s.svc.Watch(ctx, pattern)
+// With rich events enabled, each event carries the typed old and new values.
+func (s *Service[T]) Watch(ctx context.Context, pattern string) (<-chan Event[T], error) {
+	events, err := s.svc.Watch(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return typedEvents[T](ctx, events, s, s.opts), nil
 }
 
 func (s *Service[T]) saveInternal(ctx context.Context, doc *DocumentModel[T]) error {
@@ -33,6 +39,9 @@ func (s *Service[T]) saveInternal(ctx context.Context, doc *DocumentModel[T]) er
 	if doc.Saver == nil {
 		doc.Saver = s
 	}
+	if err := s.opts.check(doc.ID, doc.Data); err != nil {
+		return err
+	}
 
 	// Marshaling logic duplicated from Repository to decouple from core.Document structure here if needed,
 	// checking against the map input of Service.SaveDocument.
@@ -46,6 +55,7 @@ func (s *Service[T]) saveInternal(ctx context.Context, doc *DocumentModel[T]) er
 	if err := json.Unmarshal(importJSON, &metadata); err != nil {
 		return fmt.Errorf("unmarshal failed: %w", err)
 	}
+	s.opts.stamp(doc.ID, metadata)
 
 	return s.svc.SaveDocument(ctx, doc.ID, doc.Content, metadata)
 }
@@ -56,7 +66,7 @@ func (s *Service[T]) Get(ctx context.Context, id string) (*DocumentModel[T], err
 	if err != nil {
 		return nil, err
 	}
-	return fromCore(coreDoc, s)
+	return fromCore(coreDoc, s, s.opts)
 }
 
 // List retrieves all documents via Service.
@@ -66,15 +76,34 @@ func (s *Service[T]) List(ctx context.Context) ([]*DocumentModel[T], error) {
 		return nil, err
 	}
 
-	result := make([]*DocumentModel[T], 0, len(coreDocs))
-	for _, d := range coreDocs {
-		model, err := fromCore(d, s)
-		if err != nil {
-			return nil, err
-		}
-		result = append(result, model)
+	return fromCoreAll(coreDocs, s, s.opts)
+}
+
+// Query retrieves the documents matching q via Service.
+func (s *Service[T]) Query(ctx context.Context, q core.Query) ([]*DocumentModel[T], error) {
+	coreDocs, err := s.svc.ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return query(coreDocs, q, s, s.opts)
+}
+
+// Find retrieves the documents matching a typed query via Service.
+func (s *Service[T]) Find(ctx context.Context, q *Query[T]) ([]*DocumentModel[T], error) {
+	cq, err := q.Build()
+	if err != nil {
+		return nil, err
+	}
+	return s.Query(ctx, cq)
+}
+
+// Migrate rewrites the documents behind the version of the WithMigrations
+// registry in a single transaction, returning their IDs. See Migrate.
+func (s *Service[T]) Migrate(ctx context.Context) ([]string, error) {
+	if s.opts.migrations == nil {
+		return nil, errNoMigrations
 	}
-	return result, nil
+	return Migrate(ctx, s.svc, s.opts.migrations)
 }
 
 // Delete removes a document via Service.
@@ -90,6 +119,15 @@ func (s *Service[T]) WithTransaction(ctx context.Context, fn func(tx *Transactio
 	})
 }
 
+// WithRetryingTransaction executes a typed function within a transaction,
+// re-running it when the commit fails with core.ErrConflict.
+func (s *Service[T]) WithRetryingTransaction(ctx context.Context, maxAttempts int, fn func(tx *Transaction[T]) error) error {
+	return s.svc.WithRetryingTransaction(ctx, maxAttempts, func(coreTx core.Transaction) error {
+		tx := &Transaction[T]{tx: coreTx, svc: s}
+		return fn(tx)
+	})
+}
+
 // Transaction wraps a core.Transaction for typed operations.
 type Transaction[T any] struct {
 	tx  core.Transaction
@@ -101,6 +139,9 @@ func (t *Transaction[T]) Save(ctx context.Context, doc *DocumentModel[T]) error
 	if doc.Saver == nil {
 		doc.Saver = t
 	}
+	if err := t.svc.opts.check(doc.ID, doc.Data); err != nil {
+		return err
+	}
 
 	importJSON, err := json.Marshal(doc.Data)
 	if err != nil {
@@ -110,6 +151,7 @@ func (t *Transaction[T]) Save(ctx context.Context, doc *DocumentModel[T]) error
 	if err := json.Unmarshal(importJSON, &metadata); err != nil {
 		return fmt.Errorf("unmarshal failed: %w", err)
 	}
+	t.svc.opts.stamp(doc.ID, metadata)
 
 	coreDoc := core.Document{
 		ID:       doc.ID,
@@ -128,7 +170,50 @@ func (t *Transaction[T]) Get(ctx context.Context, id string) (*DocumentModel[T],
 	// We need fromCore helper. It is defined in repository.go in the same package.
 	// But `fromCore` takes `Saver[T]`. `Transaction[T]` needs to implement Saver[T].
 	// Saver[T] interface is `Save(ctx, doc)`. Transaction[T] has it.
-	return fromCore(coreDoc, t)
+	return fromCore(coreDoc, t, t.svc.opts)
+}
+
+// List retrieves all documents as seen by the transaction, including staged changes.
+func (t *Transaction[T]) List(ctx context.Context) ([]*DocumentModel[T], error) {
+	coreDocs, err := t.tx.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fromCoreAll(coreDocs, t, t.svc.opts)
+}
+
+// Query retrieves the documents matching q within the transaction, including staged changes.
+func (t *Transaction[T]) Query(ctx context.Context, q core.Query) ([]*DocumentModel[T], error) {
+	coreDocs, err := t.tx.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return query(coreDocs, q, t, t.svc.opts)
+}
+
+// Find retrieves the documents matching a typed query within the transaction, including staged changes.
+func (t *Transaction[T]) Find(ctx context.Context, q *Query[T]) ([]*DocumentModel[T], error) {
+	cq, err := q.Build()
+	if err != nil {
+		return nil, err
+	}
+	return t.Query(ctx, cq)
+}
+
+// Savepoint marks the current staged state of the transaction under name.
+func (t *Transaction[T]) Savepoint(ctx context.Context, name string) error {
+	return t.tx.Savepoint(ctx, name)
+}
+
+// RollbackTo discards the changes staged since the savepoint named name.
+func (t *Transaction[T]) RollbackTo(ctx context.Context, name string) error {
+	return t.tx.RollbackTo(ctx, name)
+}
+
+// Context returns a context carrying the transaction. WithTransaction calls made
+// with it (on any Service over the same repository) become savepoints of this transaction.
+func (t *Transaction[T]) Context(ctx context.Context) context.Context {
+	return t.svc.svc.ContextWithTransaction(ctx, t.tx)
 }
 
 // Delete removes a document within the transaction.
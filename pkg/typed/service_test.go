@@ -2,8 +2,13 @@ package typed_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aretw0/loam/pkg/adapters/fs"
 	"github.com/aretw0/loam/pkg/core"
 	"github.com/aretw0/loam/pkg/typed"
 )
@@ -100,3 +105,513 @@ func TestTypedService_TransactionRollback(t *testing.T) {
 		t.Error("Document should not exist after rollback")
 	}
 }
+
+func TestTypedService_NestedTransaction(t *testing.T) {
+	svc, _ := setupService(t)
+	typedSvc := typed.NewService[UserProfile](svc)
+	ctx := context.Background()
+
+	err := typedSvc.WithTransaction(ctx, func(tx *typed.Transaction[UserProfile]) error {
+		if err := tx.Save(ctx, &typed.DocumentModel[UserProfile]{ID: "users/kept", Data: UserProfile{Name: "Kept"}}); err != nil {
+			return err
+		}
+
+		// A failing sub-batch only rolls back its own changes.
+		subErr := typedSvc.WithTransaction(tx.Context(ctx), func(sub *typed.Transaction[UserProfile]) error {
+			sub.Save(ctx, &typed.DocumentModel[UserProfile]{ID: "users/dropped", Data: UserProfile{Name: "Dropped"}})
+			return errors.New("validation failed")
+		})
+		if subErr == nil {
+			t.Error("expected nested error to be returned")
+		}
+
+		// A successful sub-batch is kept.
+		return typedSvc.WithTransaction(tx.Context(ctx), func(sub *typed.Transaction[UserProfile]) error {
+			return sub.Save(ctx, &typed.DocumentModel[UserProfile]{ID: "users/nested", Data: UserProfile{Name: "Nested"}})
+		})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	if _, err := typedSvc.Get(ctx, "users/kept"); err != nil {
+		t.Errorf("expected outer change to be committed: %v", err)
+	}
+	if _, err := typedSvc.Get(ctx, "users/nested"); err != nil {
+		t.Errorf("expected nested change to be committed: %v", err)
+	}
+	if _, err := typedSvc.Get(ctx, "users/dropped"); err == nil {
+		t.Error("expected rolled back nested change to be discarded")
+	}
+}
+
+func TestTypedService_WatchRichEvents(t *testing.T) {
+	repo := fs.NewRepository(fs.Config{
+		Path:       t.TempDir(),
+		Gitless:    true,
+		SystemDir:  ".loam",
+		RichEvents: true,
+	})
+	ctx := context.Background()
+	if err := repo.Initialize(ctx); err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	typedSvc := typed.NewService[UserProfile](core.NewService(repo))
+
+	user := &typed.DocumentModel[UserProfile]{ID: "users/alice", Data: UserProfile{Name: "Alice", Age: 30}}
+	if err := typedSvc.Save(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	events, err := typedSvc.Watch(watchCtx, "**/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	user.Data.Age = 31
+	if err := typedSvc.Save(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case e := <-events:
+		if e.ID != "users/alice" || e.Origin != core.OriginLocal {
+			t.Fatalf("unexpected event: %+v", e.Event)
+		}
+		if e.Old == nil || e.Old.Data.Age != 30 {
+			t.Errorf("expected typed old value, got %+v", e.Old)
+		}
+		if e.New == nil || e.New.Data.Age != 31 || e.New.Data.Name != "Alice" {
+			t.Errorf("expected typed new value, got %+v", e.New)
+		}
+	case <-watchCtx.Done():
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestTypedService_Hooks(t *testing.T) {
+	repo, _ := setupRepo(t)
+	requireName := func(ctx context.Context, m *core.Mutation, next core.MutationHandler) error {
+		if m.Kind == core.MutationSave {
+			if name, _ := m.Document.Metadata["name"].(string); name == "" {
+				return &core.ValidationError{ID: m.Document.ID, Errors: []core.FieldError{{Field: "name", Message: "is required"}}}
+			}
+			m.Document.Metadata["email"] = "set-by-hook@example.com"
+		}
+		return next(ctx, m)
+	}
+	typedSvc := typed.NewService[UserProfile](core.NewService(repo, core.WithHook(requireName)))
+	ctx := context.Background()
+
+	if err := typedSvc.Save(ctx, &typed.DocumentModel[UserProfile]{ID: "users/anon"}); !errors.Is(err, core.ErrInvalidDocument) {
+		t.Errorf("expected validation error, got %v", err)
+	}
+	err := typedSvc.WithTransaction(ctx, func(tx *typed.Transaction[UserProfile]) error {
+		return tx.Save(ctx, &typed.DocumentModel[UserProfile]{ID: "users/anon"})
+	})
+	if !errors.Is(err, core.ErrInvalidDocument) {
+		t.Errorf("expected validation error in transaction, got %v", err)
+	}
+
+	if err := typedSvc.Save(ctx, &typed.DocumentModel[UserProfile]{ID: "users/ana", Data: UserProfile{Name: "Ana"}}); err != nil {
+		t.Fatal(err)
+	}
+	user, err := typedSvc.Get(ctx, "users/ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Data.Email != "set-by-hook@example.com" {
+		t.Errorf("expected hook to set the email, got %+v", user.Data)
+	}
+}
+
+type Account struct {
+	Name  string   `json:"name" validate:"required"`
+	Email string   `json:"email" validate:"regex=^[^@]+@[^@]+$"`
+	Plan  string   `json:"plan,omitempty" validate:"oneof=free pro"`
+	Seats int      `json:"seats" validate:"min=1,max=50"`
+	Tags  []string `json:"tags,omitempty" validate:"max=2"`
+}
+
+func (a Account) Validate() error {
+	if a.Plan == "free" && a.Seats > 1 {
+		return &core.ValidationError{Errors: []core.FieldError{{Field: "seats", Message: "free plan has a single seat"}}}
+	}
+	return nil
+}
+
+func TestTypedService_Validation(t *testing.T) {
+	svc, _ := setupService(t)
+	ctx := context.Background()
+	accounts := typed.NewService[Account](svc, typed.WithValidation())
+
+	err := accounts.Save(ctx, &typed.DocumentModel[Account]{ID: "accounts/bad", Data: Account{
+		Email: "nope", Plan: "free", Seats: 3, Tags: []string{"a", "b", "c"},
+	}})
+	var verr *core.ValidationError
+	if !errors.As(err, &verr) || verr.ID != "accounts/bad" {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	fields := make(map[string]string)
+	for _, fe := range verr.Errors {
+		fields[fe.Field] += fe.Message
+	}
+	if len(verr.Errors) != 4 || fields["name"] != "is required" || fields["email"] == "" || fields["tags"] == "" || fields["seats"] != "free plan has a single seat" {
+		t.Errorf("unexpected field errors: %v", verr.Errors)
+	}
+
+	err = accounts.WithTransaction(ctx, func(tx *typed.Transaction[Account]) error {
+		return tx.Save(ctx, &typed.DocumentModel[Account]{ID: "accounts/tx", Data: Account{Name: "tx", Email: "tx@example.com"}})
+	})
+	if !errors.Is(err, core.ErrInvalidDocument) {
+		t.Errorf("expected invalid seats in transaction, got %v", err)
+	}
+
+	// Documents written without validation (e.g. by another service).
+	raw := typed.NewService[Account](svc)
+	for _, id := range []string{"accounts/ok", "accounts/legacy", "accounts/broken"} {
+		a := Account{Name: "ok", Email: "ok@example.com", Seats: 1}
+		if id != "accounts/ok" {
+			a.Seats = 0
+		}
+		if err := raw.Save(ctx, &typed.DocumentModel[Account]{ID: id, Data: a}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := accounts.Get(ctx, "accounts/legacy"); !errors.Is(err, core.ErrInvalidDocument) {
+		t.Errorf("expected invalid document on load, got %v", err)
+	}
+	_, err = accounts.List(ctx)
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) || len(joined.Unwrap()) != 2 {
+		t.Errorf("expected both invalid documents to be reported, got %v", err)
+	}
+
+	lenient := typed.NewService[Account](svc, typed.WithLenientValidation())
+	docs, err := lenient.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := 0
+	for _, d := range docs {
+		if len(d.Diagnostics) > 0 {
+			invalid++
+			if d.Diagnostics[0].Field != "seats" {
+				t.Errorf("unexpected diagnostics for %s: %v", d.ID, d.Diagnostics)
+			}
+		}
+	}
+	if len(docs) != 3 || invalid != 2 {
+		t.Errorf("expected 3 documents with 2 diagnosed, got %d and %d", len(docs), invalid)
+	}
+}
+
+// Contact is UserProfile after two schema changes: "name" was split into
+// first/last names (v1), and "email" became a list (v2).
+type Contact struct {
+	FirstName string   `json:"first_name"`
+	LastName  string   `json:"last_name"`
+	Emails    []string `json:"emails"`
+}
+
+func contactMigrations() *typed.Migrations {
+	return typed.NewMigrations("contacts/*").
+		Register(func(m map[string]any) (map[string]any, error) {
+			name, _ := m["name"].(string)
+			first, last, _ := strings.Cut(name, " ")
+			delete(m, "name")
+			m["first_name"], m["last_name"] = first, last
+			return m, nil
+		}).
+		Register(func(m map[string]any) (map[string]any, error) {
+			email, _ := m["email"].(string)
+			if email == "" {
+				return nil, errors.New("missing email")
+			}
+			delete(m, "email")
+			m["emails"] = []any{email}
+			return m, nil
+		})
+}
+
+func TestTypedService_Migrations(t *testing.T) {
+	svc, _ := setupService(t)
+	ctx := context.Background()
+
+	old := typed.NewService[UserProfile](svc)
+	for _, id := range []string{"contacts/ada", "users/bob"} {
+		if err := old.Save(ctx, &typed.DocumentModel[UserProfile]{ID: id, Content: "notes", Data: UserProfile{Name: "Ada Lovelace", Email: "ada@example.com"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	contacts := typed.NewService[Contact](svc, typed.WithMigrations(contactMigrations()))
+	doc, err := contacts.Get(ctx, "contacts/ada")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Data.FirstName != "Ada" || doc.Data.LastName != "Lovelace" || len(doc.Data.Emails) != 1 {
+		t.Errorf("document not upgraded on read: %+v", doc.Data)
+	}
+	if stored, _ := svc.GetDocument(ctx, "contacts/ada"); stored.Metadata["name"] != "Ada Lovelace" {
+		t.Errorf("read rewrote the stored document: %v", stored.Metadata)
+	}
+
+	// Saves are stamped with the current version, so they are not migrated again.
+	if err := contacts.Save(ctx, &typed.DocumentModel[Contact]{ID: "contacts/grace", Data: Contact{FirstName: "Grace", Emails: []string{"grace@example.com"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := svc.GetDocument(ctx, "contacts/grace"); fmt.Sprint(stored.Metadata[typed.VersionKey]) != "2" {
+		t.Errorf("saved document not stamped: %v", stored.Metadata)
+	}
+
+	ids, err := contacts.Migrate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "contacts/ada" {
+		t.Errorf("migrated %v, want [contacts/ada]", ids)
+	}
+	stored, _ := svc.GetDocument(ctx, "contacts/ada")
+	if stored.Metadata["first_name"] != "Ada" || stored.Metadata["name"] != nil || stored.Content != "notes" {
+		t.Errorf("unexpected migrated document: %+v", stored)
+	}
+	if other, _ := svc.GetDocument(ctx, "users/bob"); other.Metadata["name"] != "Ada Lovelace" {
+		t.Errorf("document outside the pattern migrated: %v", other.Metadata)
+	}
+	if ids, err := contacts.Migrate(ctx); err != nil || len(ids) != 0 {
+		t.Errorf("second migration = %v, %v", ids, err)
+	}
+
+	// A failing step writes nothing.
+	if err := old.Save(ctx, &typed.DocumentModel[UserProfile]{ID: "contacts/nomail", Data: UserProfile{Name: "No Mail"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := old.Save(ctx, &typed.DocumentModel[UserProfile]{ID: "contacts/alan", Data: UserProfile{Name: "Alan Turing", Email: "alan@example.com"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := contacts.Migrate(ctx); err == nil || !strings.Contains(err.Error(), "missing email") {
+		t.Errorf("expected step error, got %v", err)
+	}
+	if stored, _ := svc.GetDocument(ctx, "contacts/alan"); stored.Metadata[typed.VersionKey] != nil {
+		t.Errorf("failed migration wrote documents: %v", stored.Metadata)
+	}
+}
+
+func TestMigrate_RunsStepsOnce(t *testing.T) {
+	svc, _ := setupService(t)
+	ctx := context.Background()
+	for _, id := range []string{"notes/a", "notes/b", "notes/c"} {
+		if err := svc.SaveDocument(ctx, id, "", core.Metadata{"title": id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runs := 0
+	m := typed.NewMigrations("notes/*").Register(func(meta map[string]any) (map[string]any, error) {
+		runs++ // Steps may have side effects, like the scripts of loam migrate.
+		meta["run"] = runs
+		return meta, nil
+	})
+	ids, err := typed.Migrate(ctx, svc, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || runs != 3 {
+		t.Errorf("migrated %v with %d step runs, want 3 documents and 3 runs", ids, runs)
+	}
+}
+
+type Audit struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Issue struct {
+	Audit
+	Title    string   `json:"title"`
+	Status   string   `json:"status"`
+	Priority int      `json:"priority"`
+	Labels   []string `json:"labels,omitempty"`
+	Owner    struct {
+		Email string `json:"email"`
+	} `json:"owner"`
+	Parent *Issue `json:"parent,omitempty"`
+}
+
+func TestTypedService_Find(t *testing.T) {
+	svc, _ := setupService(t)
+	ctx := context.Background()
+	issues := typed.NewService[Issue](svc)
+
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, status := range []string{"open", "closed", "open", "open"} {
+		issue := Issue{Title: fmt.Sprint("issue ", i), Status: status, Priority: i * 5, Labels: []string{"bug"}}
+		issue.CreatedAt = day.AddDate(0, 0, i)
+		issue.Owner.Email = fmt.Sprintf("dev%d@example.com", i%2)
+		if err := issues.Save(ctx, &typed.DocumentModel[Issue]{ID: fmt.Sprint("issues/", i), Data: issue}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := svc.SaveDocument(ctx, "notes/open", "", core.Metadata{"status": "open"}); err != nil {
+		t.Fatal(err)
+	}
+
+	status := typed.Where(func(i *Issue) *string { return &i.Status })
+	if status.Path() != "status" {
+		t.Errorf("path = %q", status.Path())
+	}
+	if path := typed.Where(func(i *Issue) *string { return &i.Owner.Email }).Path(); path != "owner.email" {
+		t.Errorf("nested path = %q", path)
+	}
+
+	ids := func(docs []*typed.DocumentModel[Issue]) string {
+		var out []string
+		for _, d := range docs {
+			out = append(out, d.ID)
+		}
+		return strings.Join(out, " ")
+	}
+
+	q := typed.NewQuery[Issue]().
+		Pattern("issues/*").
+		Where(status.Eq("open"), typed.Where(func(i *Issue) *int { return &i.Priority }).Gte(5)).
+		OrderBy(typed.Desc(func(i *Issue) *int { return &i.Priority }))
+	docs, err := issues.Find(ctx, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(docs); got != "issues/3 issues/2" {
+		t.Errorf("Find = %s", got)
+	}
+	if docs[0].Data.Owner.Email != "dev1@example.com" {
+		t.Errorf("result not decoded: %+v", docs[0].Data)
+	}
+
+	q = typed.NewQuery[Issue]().
+		Where(typed.Where(func(i *Issue) *time.Time { return &i.CreatedAt }).Lt(day.AddDate(0, 0, 2))).
+		Where(typed.Where(func(i *Issue) *[]string { return &i.Labels }).Contains("bug")).
+		OrderBy(typed.Asc(func(i *Issue) *string { return &i.Owner.Email })).
+		Limit(1)
+	if docs, err := issues.Find(ctx, q); err != nil || ids(docs) != "issues/0" {
+		t.Errorf("Find = %v, %v", docs, err)
+	}
+
+	err = issues.WithTransaction(ctx, func(tx *typed.Transaction[Issue]) error {
+		if err := tx.Save(ctx, &typed.DocumentModel[Issue]{ID: "issues/9", Data: Issue{Status: "closed"}}); err != nil {
+			return err
+		}
+		docs, err := tx.Find(ctx, typed.NewQuery[Issue]().Pattern("issues/*").Where(status.Ne("open")))
+		if err != nil {
+			return err
+		}
+		if got := ids(docs); got != "issues/1 issues/9" {
+			t.Errorf("transaction Find = %s", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Selectors must take the address of a JSON field of T.
+	var other string
+	bad := []*typed.Query[Issue]{
+		typed.NewQuery[Issue]().Where(typed.Where(func(i *Issue) *string { return &other }).Eq("x")),
+		typed.NewQuery[Issue]().Where(typed.Where(func(i *Issue) *string { return &i.Parent.Title }).Eq("x")),
+	}
+	for _, q := range bad {
+		if _, err := issues.Find(ctx, q); err == nil || !strings.Contains(err.Error(), "invalid field selector") {
+			t.Errorf("expected selector error, got %v", err)
+		}
+	}
+}
+
+func TestTypedService_FindBeforeMigration(t *testing.T) {
+	repo, _ := setupRepo(t)
+	svc := core.NewService(repo)
+	ctx := context.Background()
+
+	// Stored at version 0, with "name" and "email" instead of the fields of Contact.
+	old := typed.NewService[UserProfile](svc)
+	for _, name := range []string{"Ada Lovelace", "Alan Turing"} {
+		id := "contacts/" + strings.ToLower(strings.Fields(name)[0])
+		if err := old.Save(ctx, &typed.DocumentModel[UserProfile]{ID: id, Data: UserProfile{Name: name, Email: id + "@example.com"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	contacts := typed.NewService[Contact](svc, typed.WithMigrations(contactMigrations()))
+	if err := contacts.Save(ctx, &typed.DocumentModel[Contact]{ID: "contacts/grace", Data: Contact{FirstName: "Grace", LastName: "Hopper", Emails: []string{"grace@example.com"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	lastName := func(c *Contact) *string { return &c.LastName }
+	q := typed.NewQuery[Contact]().
+		Where(typed.Where(lastName).Ne("Turing")).
+		OrderBy(typed.Asc(lastName))
+	check := func(name string, docs []*typed.DocumentModel[Contact], err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var got []string
+		for _, d := range docs {
+			got = append(got, d.ID)
+		}
+		if strings.Join(got, " ") != "contacts/grace contacts/ada" {
+			t.Errorf("%s = %v", name, got)
+		}
+	}
+
+	docs, err := contacts.Find(ctx, q)
+	check("Service.Find", docs, err)
+	docs, err = typed.NewRepository[Contact](repo, typed.WithMigrations(contactMigrations())).Find(ctx, q)
+	check("Repository.Find", docs, err)
+	err = contacts.WithTransaction(ctx, func(tx *typed.Transaction[Contact]) error {
+		docs, err := tx.Find(ctx, q)
+		check("Transaction.Find", docs, err)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored, _ := svc.GetDocument(ctx, "contacts/ada"); stored.Metadata["name"] != "Ada Lovelace" {
+		t.Errorf("Find rewrote the stored document: %v", stored.Metadata)
+	}
+}
+
+type Ticket struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+func TestTypedService_FindByIDField(t *testing.T) {
+	svc, _ := setupService(t)
+	ctx := context.Background()
+	tickets := typed.NewService[Ticket](svc)
+
+	// The "id" metadata field is unrelated to the document ID.
+	for docID, ticketID := range map[string]string{"tickets/1": "JIRA-7", "tickets/2": "JIRA-8"} {
+		if err := tickets.Save(ctx, &typed.DocumentModel[Ticket]{ID: docID, Data: Ticket{ID: ticketID, Title: docID}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	q := typed.NewQuery[Ticket]().Where(typed.Where(func(t *Ticket) *string { return &t.ID }).Eq("JIRA-8"))
+	docs, err := tickets.Find(ctx, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].ID != "tickets/2" {
+		t.Errorf("expected the condition to match the id field, got %v", docs)
+	}
+
+	q = typed.NewQuery[Ticket]().Where(typed.Where(func(t *Ticket) *string { return &t.ID }).Eq("tickets/1"))
+	if docs, err := tickets.Find(ctx, q); err != nil || len(docs) != 0 {
+		t.Errorf("expected the id field not to match the document ID, got %v, %v", docs, err)
+	}
+}
@@ -0,0 +1,66 @@
+package typed
+
+import (
+	"errors"
+
+	"github.com/aretw0/loam/pkg/core"
+	"github.com/aretw0/loam/pkg/schema"
+)
+
+// Option configures a typed Repository or Service.
+type Option func(*options)
+
+type options struct {
+	validate   bool
+	lenient    bool
+	migrations *Migrations
+}
+
+// WithValidation validates T with its validate struct tags (required, min, max,
+// pattern/regex, oneof) and its Validate() error method, if any (see
+// schema.ValidateStruct). Saves of invalid documents and loads of invalid
+// documents fail with a *core.ValidationError listing every field error.
+func WithValidation() Option {
+	return func(o *options) {
+		o.validate = true
+	}
+}
+
+// WithLenientValidation validates like WithValidation on save, but loads invalid
+// documents, reporting their field errors in DocumentModel.Diagnostics.
+func WithLenientValidation() Option {
+	return func(o *options) {
+		o.validate = true
+		o.lenient = true
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// check validates the data of a document before it is saved.
+func (o options) check(id string, data any) error {
+	if !o.validate {
+		return nil
+	}
+	if fields := schema.ValidateStruct(data); len(fields) > 0 {
+		return &core.ValidationError{ID: id, Errors: fields}
+	}
+	return nil
+}
+
+// checkLoaded validates a loaded document: in lenient mode the errors become its diagnostics.
+func checkLoaded[T any](o options, doc *DocumentModel[T]) error {
+	err := o.check(doc.ID, doc.Data)
+	var verr *core.ValidationError
+	if o.lenient && errors.As(err, &verr) {
+		doc.Diagnostics = verr.Errors
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,278 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// Headers set on every delivery.
+const (
+	SignatureHeader = "X-Loam-Signature" // "sha256=" + hex HMAC-SHA256 of the body; set if the hook has a secret.
+	EventHeader     = "X-Loam-Event"     // Event type (CREATE, MODIFY, DELETE).
+	DeliveryHeader  = "X-Loam-Delivery"  // Delivery ID, stable across retries (use it to deduplicate).
+)
+
+// Defaults of the Dispatcher.
+const (
+	DefaultMaxAttempts = 5
+	DefaultBackoff     = time.Second
+	DefaultMaxBackoff  = time.Minute
+	DefaultTimeout     = 10 * time.Second
+)
+
+// Option configures a Dispatcher.
+type Option func(*Dispatcher)
+
+// WithHTTPClient sets the client used for deliveries.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithBackoff sets the delay before the first retry and its upper bound; the delay doubles on each retry.
+func WithBackoff(initial, maxDelay time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.backoff = initial
+		d.maxBackoff = maxDelay
+	}
+}
+
+// WithMaxAttempts sets how many times a delivery is tried before it is kept as undelivered.
+func WithMaxAttempts(n int) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = n
+	}
+}
+
+// WithLogger sets the logger for delivery failures.
+func WithLogger(logger *slog.Logger) Option {
+	return func(d *Dispatcher) {
+		d.logger = logger
+	}
+}
+
+// Dispatcher delivers the changes of a service to the configured webhooks.
+type Dispatcher struct {
+	svc   *core.Service
+	hooks []Hook
+	store *store
+
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	logger      *slog.Logger
+}
+
+// NewDispatcher creates a dispatcher for the webhooks of cfg. systemPath is the
+// vault's system directory, where deliveries and statuses are persisted.
+func NewDispatcher(svc *core.Service, systemPath string, cfg Config, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		svc:         svc,
+		hooks:       cfg.Webhooks,
+		store:       newStore(systemPath),
+		client:      &http.Client{Timeout: DefaultTimeout},
+		maxAttempts: DefaultMaxAttempts,
+		backoff:     DefaultBackoff,
+		maxBackoff:  DefaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run subscribes to the service's changes and delivers them until ctx is done.
+// Deliveries left by a previous run, including undelivered ones, are retried first.
+// Each webhook receives its deliveries in order; a failing webhook does not delay the others.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	if len(d.hooks) == 0 {
+		return nil
+	}
+	events, err := d.svc.Watch(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	notify := make(map[string]chan struct{}, len(d.hooks))
+	for _, h := range d.hooks {
+		if err := d.store.requeue(h.Name); err != nil {
+			return fmt.Errorf("failed to requeue deliveries of %s: %w", h.Name, err)
+		}
+		ch := make(chan struct{}, 1)
+		notify[h.Name] = ch
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.worker(ctx, h, ch)
+		}()
+	}
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			for _, h := range d.hooks {
+				if !h.Matches(e) {
+					continue
+				}
+				// Persisted before delivery, so it survives a crash.
+				rec := record{Payload: newPayload(d.store.nextID(), h.Name, e)}
+				if err := d.store.save(h.Name, rec); err != nil {
+					d.logError("failed to persist delivery", h.Name, err)
+					continue
+				}
+				select {
+				case notify[h.Name] <- struct{}{}:
+				default: // The worker is already due to rescan.
+				}
+			}
+		}
+	}
+}
+
+// worker delivers the pending deliveries of a webhook, oldest first.
+func (d *Dispatcher) worker(ctx context.Context, h Hook, notify <-chan struct{}) {
+	for {
+		pending, _, err := d.store.list(h.Name)
+		if err != nil {
+			d.logError("failed to list deliveries", h.Name, err)
+		}
+		for _, id := range pending {
+			if !d.deliver(ctx, h, id) {
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-notify:
+		}
+	}
+}
+
+// deliver tries a delivery until it succeeds or its attempts are exhausted.
+// It returns false if ctx was done first.
+func (d *Dispatcher) deliver(ctx context.Context, h Hook, id string) bool {
+	rec, err := d.store.load(h.Name, id)
+	if err != nil {
+		d.logError("failed to load delivery", h.Name, err)
+		return ctx.Err() == nil
+	}
+
+	delay := d.backoff
+	for {
+		rec.Attempts++
+		status, err := d.post(ctx, h, rec.Payload)
+		if ctx.Err() != nil {
+			return false // Interrupted: the delivery stays pending.
+		}
+		now := time.Now()
+		d.updateStatus(h.Name, func(st *HookStatus) {
+			st.LastAttempt = now
+			st.LastStatus = status
+			st.LastError = ""
+			if err != nil {
+				st.LastError = err.Error()
+				return
+			}
+			st.LastSuccess = now
+			st.Delivered++
+		})
+
+		if err == nil {
+			if err := d.store.remove(h.Name, id); err != nil {
+				d.logError("failed to remove delivery", h.Name, err)
+			}
+			return true
+		}
+
+		rec.LastError = err.Error()
+		rec.Failed = rec.Attempts >= d.maxAttempts
+		if err := d.store.save(h.Name, rec); err != nil {
+			d.logError("failed to persist delivery", h.Name, err)
+		}
+		if rec.Failed {
+			d.logError("delivery failed, kept as undelivered", h.Name, fmt.Errorf("%s: %s", id, rec.LastError))
+			d.updateStatus(h.Name, func(st *HookStatus) { st.Failed++ })
+			return true
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+		delay = min(delay*2, d.maxBackoff)
+	}
+}
+
+// post sends the payload, returning the HTTP status (0 if no response).
+func (d *Dispatcher) post(ctx context.Context, h Hook, p Payload) (int, error) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(p.Type))
+	req.Header.Set(DeliveryHeader, p.Delivery)
+	if secret := h.secret(); secret != "" {
+		req.Header.Set(SignatureHeader, Sign(secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the X-Loam-Signature value of body: "sha256=" followed by the hex HMAC-SHA256.
+// Receivers should compare it with hmac.Equal.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) updateStatus(hook string, fn func(*HookStatus)) {
+	if err := d.store.update(hook, fn); err != nil {
+		d.logError("failed to persist status", hook, err)
+	}
+}
+
+func (d *Dispatcher) logError(msg, hook string, err error) {
+	if d.logger != nil {
+		d.logger.Error(msg, "webhook", hook, "err", err)
+	}
+}
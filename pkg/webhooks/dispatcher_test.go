@@ -0,0 +1,169 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// eventRepository is a repository whose changes are fed through a channel.
+type eventRepository struct {
+	events chan core.Event
+}
+
+func (r *eventRepository) Save(ctx context.Context, doc core.Document) error { return nil }
+func (r *eventRepository) Get(ctx context.Context, id string) (core.Document, error) {
+	return core.Document{}, errors.New("not found")
+}
+func (r *eventRepository) List(ctx context.Context) ([]core.Document, error) { return nil, nil }
+func (r *eventRepository) Delete(ctx context.Context, id string) error       { return nil }
+func (r *eventRepository) Initialize(ctx context.Context) error              { return nil }
+func (r *eventRepository) Watch(ctx context.Context, pattern string) (<-chan core.Event, error) {
+	return r.events, nil
+}
+
+// receiver is a webhook endpoint failing the first `failures` requests.
+type receiver struct {
+	t        *testing.T
+	secret   string
+	failures atomic.Int32
+	received chan Payload
+}
+
+func newReceiver(t *testing.T, secret string) (*receiver, *httptest.Server) {
+	rc := &receiver{t: t, secret: secret, received: make(chan Payload, 10)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		if !hmac.Equal([]byte(req.Header.Get(SignatureHeader)), []byte(Sign(rc.secret, body))) {
+			t.Errorf("bad signature %q", req.Header.Get(SignatureHeader))
+		}
+		if rc.failures.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var p Payload
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Errorf("bad payload: %v", err)
+		}
+		if req.Header.Get(DeliveryHeader) != p.Delivery || req.Header.Get(EventHeader) != string(p.Type) {
+			t.Errorf("headers do not match payload %+v", p)
+		}
+		rc.received <- p
+	}))
+	t.Cleanup(srv.Close)
+	return rc, srv
+}
+
+func (rc *receiver) next() Payload {
+	rc.t.Helper()
+	select {
+	case p := <-rc.received:
+		return p
+	case <-time.After(5 * time.Second):
+		rc.t.Fatal("timed out waiting for delivery")
+	}
+	return Payload{}
+}
+
+func TestDispatcher(t *testing.T) {
+	systemPath := t.TempDir()
+	rc, srv := newReceiver(t, "s3cret")
+	cfg := Config{Webhooks: []Hook{{
+		Name:    "search",
+		URL:     srv.URL,
+		Secret:  "s3cret",
+		Pattern: "posts/**",
+		Events:  []core.EventType{core.EventCreate, core.EventModify},
+	}}}
+	if err := writeJSON(systemPath+"/"+ConfigName, cfg); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadConfig(systemPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := func(maxAttempts int) (*eventRepository, context.CancelFunc, chan struct{}) {
+		repo := &eventRepository{events: make(chan core.Event, 10)}
+		d := NewDispatcher(core.NewService(repo), systemPath, loaded,
+			WithBackoff(10*time.Millisecond, 20*time.Millisecond), WithMaxAttempts(maxAttempts))
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if err := d.Run(ctx); err != nil {
+				t.Errorf("run failed: %v", err)
+			}
+		}()
+		return repo, cancel, done
+	}
+
+	t.Run("Delivers With Retries", func(t *testing.T) {
+		repo, cancel, done := start(5)
+		defer func() { cancel(); <-done }()
+
+		rc.failures.Store(2)
+		repo.events <- core.Event{Type: core.EventDelete, ID: "posts/old"}   // Filtered by type.
+		repo.events <- core.Event{Type: core.EventCreate, ID: "pages/about"} // Filtered by pattern.
+		repo.events <- core.Event{Type: core.EventCreate, ID: "posts/hello", Path: "posts/hello.md", Timestamp: 42}
+
+		p := rc.next()
+		if p.ID != "posts/hello" || p.Type != core.EventCreate || p.Hook != "search" || p.Path != "posts/hello.md" || p.Timestamp != 42 {
+			t.Errorf("unexpected payload: %+v", p)
+		}
+
+		statuses := waitStatus(t, systemPath, func(st HookStatus) bool { return st.Delivered == 1 })
+		if st := statuses[0]; st.LastStatus != http.StatusOK || st.Pending != 0 || st.LastError != "" {
+			t.Errorf("unexpected status: %+v", st)
+		}
+	})
+
+	t.Run("Keeps Undelivered Events", func(t *testing.T) {
+		repo, cancel, done := start(2)
+		rc.failures.Store(100)
+		repo.events <- core.Event{Type: core.EventModify, ID: "posts/hello"}
+
+		statuses := waitStatus(t, systemPath, func(st HookStatus) bool { return st.Undelivered == 1 && st.Failed == 1 })
+		if st := statuses[0]; st.LastStatus != http.StatusServiceUnavailable || st.LastError == "" {
+			t.Errorf("unexpected status: %+v", st)
+		}
+		cancel()
+		<-done
+
+		// A new dispatcher retries it.
+		rc.failures.Store(0)
+		_, cancel, done = start(2)
+		defer func() { cancel(); <-done }()
+		if p := rc.next(); p.ID != "posts/hello" || p.Type != core.EventModify {
+			t.Errorf("unexpected payload: %+v", p)
+		}
+		waitStatus(t, systemPath, func(st HookStatus) bool { return st.Undelivered == 0 && st.Pending == 0 })
+	})
+}
+
+func waitStatus(t *testing.T, systemPath string, ok func(HookStatus) bool) []HookStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		statuses, err := Status(systemPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(statuses) == 1 && ok(statuses[0]) {
+			return statuses
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected status: %+v", statuses)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
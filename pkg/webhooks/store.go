@@ -0,0 +1,232 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// record is a delivery kept until the webhook acknowledges it.
+type record struct {
+	Payload   Payload `json:"payload"`
+	Attempts  int     `json:"attempts"`
+	LastError string  `json:"last_error,omitempty"`
+	Failed    bool    `json:"failed,omitempty"` // Attempts exhausted; retried when a dispatcher starts.
+}
+
+// HookStatus is the delivery status of a webhook.
+type HookStatus struct {
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	Delivered   int       `json:"delivered"`
+	Failed      int       `json:"failed"`      // Deliveries whose attempts were exhausted.
+	Pending     int       `json:"pending"`     // Deliveries waiting to be (re)tried.
+	Undelivered int       `json:"undelivered"` // Failed deliveries kept for the next dispatcher start.
+	LastAttempt time.Time `json:"last_attempt,omitzero"`
+	LastSuccess time.Time `json:"last_success,omitzero"`
+	LastStatus  int       `json:"last_status,omitempty"` // HTTP status of the last attempt.
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// store persists deliveries and statuses under <SystemDir>/webhooks:
+// pending/<hook>/<delivery>.json and status.json.
+type store struct {
+	dir string
+
+	mu       sync.Mutex
+	statuses map[string]*HookStatus
+	last     int64 // Last delivery sequence, to keep IDs ordered.
+}
+
+func newStore(systemPath string) *store {
+	return &store{dir: filepath.Join(systemPath, stateDirName)}
+}
+
+func (s *store) pendingDir(hook string) string {
+	return filepath.Join(s.dir, "pending", hook)
+}
+
+// nextID returns a new delivery ID; IDs sort in creation order.
+func (s *store) nextID() string {
+	s.mu.Lock()
+	seq := max(time.Now().UnixNano(), s.last+1)
+	s.last = seq
+	s.mu.Unlock()
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%020d-%s", seq, hex.EncodeToString(suffix))
+}
+
+func (s *store) save(hook string, rec record) error {
+	path := filepath.Join(s.pendingDir(hook), rec.Payload.Delivery+".json")
+	return writeJSON(path, rec)
+}
+
+func (s *store) load(hook, id string) (record, error) {
+	var rec record
+	data, err := os.ReadFile(filepath.Join(s.pendingDir(hook), id+".json"))
+	if err != nil {
+		return rec, err
+	}
+	return rec, json.Unmarshal(data, &rec)
+}
+
+func (s *store) remove(hook, id string) error {
+	err := os.Remove(filepath.Join(s.pendingDir(hook), id+".json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// list returns the IDs of a webhook's deliveries, oldest first, split into
+// pending and failed ones.
+func (s *store) list(hook string) (pending, failed []string, err error) {
+	entries, err := os.ReadDir(s.pendingDir(hook))
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		rec, err := s.load(hook, id)
+		if err != nil {
+			continue // Left incomplete by a crash; the delivery was never attempted.
+		}
+		if rec.Failed {
+			failed = append(failed, id)
+		} else {
+			pending = append(pending, id)
+		}
+	}
+	return pending, failed, nil
+}
+
+// requeue makes the failed deliveries of a webhook pending again, with fresh attempts.
+func (s *store) requeue(hook string) error {
+	_, failed, err := s.list(hook)
+	if err != nil {
+		return err
+	}
+	for _, id := range failed {
+		rec, err := s.load(hook, id)
+		if err != nil {
+			continue
+		}
+		rec.Failed = false
+		rec.Attempts = 0
+		if err := s.save(hook, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadStatuses reads status.json; a missing file means no delivery was made yet.
+func (s *store) loadStatuses() (map[string]*HookStatus, error) {
+	statuses := make(map[string]*HookStatus)
+	data, err := os.ReadFile(filepath.Join(s.dir, "status.json"))
+	if os.IsNotExist(err) {
+		return statuses, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &statuses); err != nil {
+		return nil, fmt.Errorf("invalid webhooks status: %w", err)
+	}
+	return statuses, nil
+}
+
+// update applies fn to the status of a webhook and persists every status.
+func (s *store) update(hook string, fn func(*HookStatus)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.statuses == nil {
+		statuses, err := s.loadStatuses()
+		if err != nil {
+			return err
+		}
+		s.statuses = statuses
+	}
+	st, ok := s.statuses[hook]
+	if !ok {
+		st = &HookStatus{Name: hook}
+		s.statuses[hook] = st
+	}
+	fn(st)
+	return writeJSON(filepath.Join(s.dir, "status.json"), s.statuses)
+}
+
+// writeJSON writes v to path atomically.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Status returns the delivery status of every webhook configured in systemPath.
+func Status(systemPath string) ([]HookStatus, error) {
+	cfg, err := LoadConfig(systemPath)
+	if err != nil {
+		return nil, err
+	}
+	s := newStore(systemPath)
+	statuses, err := s.loadStatuses()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]HookStatus, 0, len(cfg.Webhooks))
+	for _, h := range cfg.Webhooks {
+		st := HookStatus{Name: h.Name}
+		if saved, ok := statuses[h.Name]; ok {
+			st = *saved
+		}
+		st.URL = h.URL
+		pending, failed, err := s.list(h.Name)
+		if err != nil {
+			return nil, err
+		}
+		st.Pending = len(pending)
+		st.Undelivered = len(failed)
+		out = append(out, st)
+	}
+	return out, nil
+}
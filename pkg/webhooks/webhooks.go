@@ -0,0 +1,137 @@
+// Package webhooks notifies external systems of vault changes.
+//
+// Webhooks are configured in the vault, in <SystemDir>/webhooks.json. The Dispatcher
+// subscribes to the service's Watch and POSTs a JSON payload, signed with HMAC-SHA256,
+// to every webhook whose ID glob and event types match the change. Failed deliveries
+// are retried with exponential backoff; deliveries not yet acknowledged are kept in
+// <SystemDir>/webhooks/pending and retried when a dispatcher starts again.
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/aretw0/loam/pkg/core"
+)
+
+const (
+	// ConfigName is the file, inside the system directory, holding the webhooks.
+	ConfigName = "webhooks.json"
+	// stateDirName is the directory, inside the system directory, holding delivery state.
+	stateDirName = "webhooks"
+)
+
+// Hook is a webhook endpoint.
+type Hook struct {
+	Name      string           `json:"name"`
+	URL       string           `json:"url"`
+	Secret    string           `json:"secret,omitempty"`     // Key of the X-Loam-Signature HMAC.
+	SecretEnv string           `json:"secret_env,omitempty"` // Environment variable holding the secret, if Secret is empty.
+	Pattern   string           `json:"pattern,omitempty"`    // Glob on the document ID; empty matches all.
+	Events    []core.EventType `json:"events,omitempty"`     // Event types to deliver; empty means all.
+}
+
+// Matches reports whether the event must be delivered to the hook.
+func (h Hook) Matches(e core.Event) bool {
+	if len(h.Events) > 0 && !slices.Contains(h.Events, e.Type) {
+		return false
+	}
+	if h.Pattern == "" || h.Pattern == "*" {
+		return true
+	}
+	ok, _ := doublestar.Match(h.Pattern, e.ID)
+	return ok
+}
+
+func (h Hook) secret() string {
+	if h.Secret == "" && h.SecretEnv != "" {
+		return os.Getenv(h.SecretEnv)
+	}
+	return h.Secret
+}
+
+// validName restricts webhook names, which name their state directories.
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// Config is the content of the webhooks configuration file.
+type Config struct {
+	Webhooks []Hook `json:"webhooks"`
+}
+
+// LoadConfig reads the webhooks configured in systemPath (the vault's system directory).
+// A missing file is an empty configuration.
+func LoadConfig(systemPath string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(filepath.Join(systemPath, ConfigName))
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read webhooks config: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid webhooks config: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i, h := range cfg.Webhooks {
+		if h.Name == "" || h.URL == "" {
+			return cfg, fmt.Errorf("invalid webhooks config: webhook %d needs a name and an url", i)
+		}
+		if !validName.MatchString(h.Name) {
+			return cfg, fmt.Errorf("invalid webhooks config: webhook name %q (use letters, digits, '.', '_' and '-')", h.Name)
+		}
+		if seen[h.Name] {
+			return cfg, fmt.Errorf("invalid webhooks config: duplicate webhook %q", h.Name)
+		}
+		seen[h.Name] = true
+	}
+	return cfg, nil
+}
+
+// Payload is the JSON body POSTed to a webhook.
+type Payload struct {
+	Delivery  string           `json:"delivery"` // Unique per delivery; stable across retries.
+	Hook      string           `json:"hook"`
+	Type      core.EventType   `json:"type"`
+	ID        string           `json:"id"`
+	Path      string           `json:"path,omitempty"`
+	Timestamp int64            `json:"timestamp"`
+	Commit    string           `json:"commit,omitempty"`
+	Origin    core.EventOrigin `json:"origin,omitempty"`
+	Metadata  core.Metadata    `json:"metadata,omitempty"` // New metadata, with rich events.
+	Diff      []FieldChange    `json:"diff,omitempty"`     // Metadata changes, with rich events.
+}
+
+// FieldChange is the JSON form of core.FieldChange.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old,omitempty"`
+	New   any    `json:"new,omitempty"`
+}
+
+func newPayload(delivery, hook string, e core.Event) Payload {
+	p := Payload{
+		Delivery:  delivery,
+		Hook:      hook,
+		Type:      e.Type,
+		ID:        e.ID,
+		Path:      e.Path,
+		Timestamp: e.Timestamp,
+		Commit:    e.Commit,
+		Origin:    e.Origin,
+	}
+	if e.Document != nil {
+		p.Metadata = e.Document.Metadata
+	}
+	for _, c := range e.Diff {
+		p.Diff = append(p.Diff, FieldChange(c))
+	}
+	return p
+}